@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireBearer(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		wantStatus int
+	}{
+		{name: "missing header", token: "s3cret", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", token: "s3cret", header: "Basic s3cret", wantStatus: http.StatusUnauthorized},
+		{name: "scheme only", token: "s3cret", header: "Bearer", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: "s3cret", header: "Bearer s3cre", wantStatus: http.StatusUnauthorized},
+		{name: "token with a suffix", token: "s3cret", header: "Bearer s3cret2", wantStatus: http.StatusUnauthorized},
+		{name: "correct token", token: "s3cret", header: "Bearer s3cret", wantStatus: http.StatusOK},
+		{name: "scheme case ignored", token: "s3cret", header: "bearer s3cret", wantStatus: http.StatusOK},
+		{name: "no token configured", header: "", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			})
+			handler := requireBearer(tt.token, log.New(&logs, "", 0), next)
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", rec.Code, tt.wantStatus)
+			}
+			rejected := tt.wantStatus == http.StatusUnauthorized
+			if got := rec.Header().Get("WWW-Authenticate"); (got != "") != rejected {
+				t.Fatalf("WWW-Authenticate %q for status %d", got, rec.Code)
+			}
+			if body := rec.Body.String(); rejected == (body == "ok") {
+				t.Fatalf("body %q for status %d", body, rec.Code)
+			}
+			if logged := strings.Contains(logs.String(), "[WARN] Rejected unauthenticated POST /mcp"); logged != rejected {
+				t.Fatalf("log %q for status %d", logs.String(), rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,3164 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// splitTags splits a comma-separated tag string into trimmed, non-empty tags.
+func splitTags(tags string) []string {
+	var out []string
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// mergeTags appends the context tags to the user's tags, skipping any tag already present
+// (case-insensitively). User tags keep their order and spelling.
+func mergeTags(userTags string, contextTags []string) string {
+	if len(contextTags) == 0 {
+		return userTags
+	}
+	var merged []string
+	seen := map[string]bool{}
+	for _, t := range append(splitTags(userTags), contextTags...) {
+		key := strings.ToLower(t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, t)
+	}
+	return strings.Join(merged, ",")
+}
+
+// renderTemplate fills vars into the named template from dir. Every variable the template
+// references must be provided.
+func renderTemplate(dir, name string, vars map[string]any) (string, error) {
+	if dir == "" {
+		return "", fmt.Errorf("templates are not configured (set SIMPLE_MEMORY_TEMPLATE_DIR)")
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", fmt.Errorf("invalid template name %q", name)
+		}
+	}
+	path := filepath.Join(dir, name+".tmpl")
+	body, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("template %q not found", name)
+		}
+		return "", fmt.Errorf("failed to read template %q: %w", name, err)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %q: %w", name, err)
+	}
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// --- MCP Tool Handlers ---
+
+// trimField trims surrounding whitespace from the value of a title, tags, status, or content
+// field, unless the field is configured to be stored as given.
+func (s *SimpleMemoryServer) trimField(field, value string) string {
+	if s.preserveWhitespace[field] {
+		return value
+	}
+	return strings.TrimSpace(value)
+}
+
+// checkContentLength enforces the configured minimum length, in characters, and maximum size,
+// in bytes, of trimmed memory content.
+func (s *SimpleMemoryServer) checkContentLength(content string) error {
+	if n := utf8.RuneCountInString(content); n < s.minContentChars {
+		return fmt.Errorf("memory is too short: %d characters, minimum is %d", n, s.minContentChars)
+	}
+	if s.maxContentBytes > 0 && len(content) > s.maxContentBytes {
+		return fmt.Errorf("memory exceeds max size of %d bytes", s.maxContentBytes)
+	}
+	return nil
+}
+
+// maxHeadingChars is the longest first line splitHeading treats as a heading without a Markdown #.
+const maxHeadingChars = 80
+
+// splitHeading splits content whose first line looks like a heading into that heading and the
+// rest. A first line is a heading if it is a Markdown ATX heading ("# Title"), or if it is at
+// most maxHeadingChars long, does not end in sentence punctuation, and is not a list item,
+// quote, table row, or code fence. Content without a non-empty rest is never split.
+func splitHeading(content string) (string, string, bool) {
+	first, rest, found := strings.Cut(strings.TrimLeft(content, " \t\r\n"), "\n")
+	if !found || strings.TrimSpace(rest) == "" {
+		return "", "", false
+	}
+	rest = strings.TrimLeft(rest, "\r\n")
+	line := strings.TrimSpace(first)
+	if level := len(line) - len(strings.TrimLeft(line, "#")); level > 0 {
+		heading := strings.TrimSpace(line[level:])
+		if level > 6 || heading == "" || !strings.HasPrefix(line[level:], " ") {
+			return "", "", false
+		}
+		return heading, rest, true
+	}
+	if utf8.RuneCountInString(line) > maxHeadingChars || strings.ContainsAny(line[:1], "-*+>|`") {
+		return "", "", false
+	}
+	if last, _ := utf8.DecodeLastRuneInString(line); strings.ContainsRune(".!?,;:", last) {
+		return "", "", false
+	}
+	return line, rest, true
+}
+
+// SimpleMemoryAdd inserts a new memory into the database. When a template is named, the
+// memory content is rendered from it using the provided variables.
+func (s *SimpleMemoryServer) SimpleMemoryAdd(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	title := req.GetString("title", "")
+	tags := req.GetString("tags", "")
+	status := req.GetString("status", "")
+	var memory string
+	if name := strings.TrimSpace(req.GetString("template", "")); name != "" {
+		var vars map[string]any
+		if raw, ok := req.GetArguments()["variables"]; ok {
+			if vars, ok = raw.(map[string]any); !ok {
+				return mcp.NewToolResultError("invalid params: variables must be an object"), nil
+			}
+		}
+		rendered, err := renderTemplate(s.templateDir, name, vars)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		memory = rendered
+	} else {
+		var err error
+		memory, err = req.RequireString("memory")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+		}
+	}
+	content := s.trimField("content", memory)
+	if strings.TrimSpace(content) == "" {
+		return mcp.NewToolResultError("memory cannot be empty"), nil
+	}
+	if s.extractTitle && strings.TrimSpace(title) == "" {
+		if heading, body, ok := splitHeading(content); ok {
+			title, content = heading, s.trimField("content", body)
+		}
+	}
+	if err := s.checkContentLength(content); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var remindAt any
+	if v := strings.TrimSpace(req.GetString("remind_at", "")); v != "" {
+		ts, err := parseTimestamp(v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid params: invalid remind_at %q: %v", v, err)), nil
+		}
+		remindAt = ts
+	}
+	tags = mergeTags(s.trimField("tags", tags), s.contextTags)
+	_, err := s.db.Exec(
+		"INSERT INTO "+s.table+" (title, tags, status, content, remind_at) VALUES (?, ?, ?, ?, ?)",
+		s.trimField("title", title), tags, s.trimField("status", status), content, remindAt,
+	)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to add memory: %v", err)), nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Added simple-memory: title=%q tags=%q status=%q content=%q", title, tags, status, content)
+	}
+	return mcp.NewToolResultText("Simple-memory added."), nil
+}
+
+// fenceCode wraps content in a Markdown code fence tagged with language, using one more
+// backtick than the longest run inside content so the fence cannot close early.
+func fenceCode(content, language string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + language + "\n" + strings.TrimSuffix(content, "\n") + "\n" + fence
+}
+
+// SimpleMemoryGet returns the simple-memory with the given ID as a JSON object, or just its
+// content, plain or in a Markdown code fence, for clients that display it directly.
+func (s *SimpleMemoryServer) SimpleMemoryGet(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireInt("id")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	format := strings.ToLower(strings.TrimSpace(req.GetString("format", "json")))
+	if format != "json" && format != "plain" && format != "markdown_code" {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid format %q: must be json, plain, or markdown_code", format)), nil
+	}
+	language := strings.TrimSpace(req.GetString("language", ""))
+	if strings.ContainsFunc(language, func(r rune) bool { return unicode.IsSpace(r) || r == '`' }) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid language %q: must not contain spaces or backticks", language)), nil
+	}
+	rows, err := s.db.Query("SELECT id, title, tags, status, content, CAST(created_at AS TEXT) FROM "+s.view+" WHERE id = ?", id)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memory: %v", err)), nil
+	}
+	defer rows.Close()
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memory: %v", err)), nil
+	}
+	if len(memories) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no memory found with id %d", id)), nil
+	}
+	switch format {
+	case "plain":
+		return mcp.NewToolResultText(memories[0].Content), nil
+	case "markdown_code":
+		return mcp.NewToolResultText(fenceCode(memories[0].Content, language)), nil
+	}
+	out, err := json.Marshal(memories[0])
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode simple-memory: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// SimpleMemoryUpdate edits the provided fields of an existing memory, keeping its ID and created_at.
+func (s *SimpleMemoryServer) SimpleMemoryUpdate(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireInt("id")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	args := req.GetArguments()
+	var (
+		sets   []string
+		params []any
+		logged []string
+	)
+	for _, field := range []struct{ param, column string }{
+		{"title", "title"},
+		{"tags", "tags"},
+		{"status", "status"},
+		{"memory", "content"},
+	} {
+		if _, ok := args[field.param]; !ok {
+			continue
+		}
+		value, err := req.RequireString(field.param)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+		}
+		value = s.trimField(field.column, value)
+		if field.column == "content" && strings.TrimSpace(value) == "" {
+			return mcp.NewToolResultError("memory cannot be empty"), nil
+		}
+		if field.column == "content" {
+			if err := s.checkContentLength(value); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+		sets = append(sets, field.column+" = ?")
+		params = append(params, value)
+		logged = append(logged, fmt.Sprintf("%s=%q", field.column, value))
+	}
+	if _, ok := args["remind_at"]; ok {
+		// An empty remind_at clears the reminder; any change re-arms it.
+		var remindAt any
+		if v := strings.TrimSpace(req.GetString("remind_at", "")); v != "" {
+			ts, err := parseTimestamp(v)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid params: invalid remind_at %q: %v", v, err)), nil
+			}
+			remindAt = ts
+		}
+		sets = append(sets, "remind_at = ?", "reminder_acked_at = NULL")
+		params = append(params, remindAt)
+		logged = append(logged, fmt.Sprintf("remind_at=%v", remindAt))
+	}
+	if len(sets) == 0 {
+		return mcp.NewToolResultError("nothing to update: provide at least one of title, tags, status, memory, or remind_at"), nil
+	}
+	params = append(params, id)
+	res, err := s.db.Exec("UPDATE "+s.table+" SET "+strings.Join(sets, ", ")+" WHERE id = ?", params...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to update memory: %v", err)), nil
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to update memory: %v", err)), nil
+	}
+	if n == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no memory found with id %d", id)), nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Updated simple-memory %d: %s", id, strings.Join(logged, " "))
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Simple-memory %d updated.", id)), nil
+}
+
+// scanMemories reads all rows into Memory values, skipping rows with empty content.
+// Rows must select id, title, tags, status, content, CAST(created_at AS TEXT) in that order;
+// the cast keeps the driver from zeroing timestamps it cannot parse.
+func scanMemories(rows *sql.Rows) ([]Memory, error) {
+	var memories []Memory
+	for rows.Next() {
+		var (
+			m         Memory
+			title     sql.NullString
+			tags      sql.NullString
+			status    sql.NullString
+			content   sql.NullString
+			createdAt sql.NullString
+		)
+		if err := rows.Scan(&m.ID, &title, &tags, &status, &content, &createdAt); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(content.String) == "" {
+			continue
+		}
+		m.Title = title.String
+		m.Tags = tags.String
+		m.Status = status.String
+		m.Content = content.String
+		m.CreatedAt = createdAt.String
+		memories = append(memories, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+// memoriesResult encodes memories as a single JSON array tool result.
+func memoriesResult(memories []Memory) (*mcp.CallToolResult, error) {
+	out, err := json.Marshal(memories)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode simple-memories: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// structuredResult encodes results in the envelope tools answer with when structured results
+// are enabled, {"results":[...],"count":n}, followed by the fields of extra, a struct of
+// tool-specific totals, unless it is nil. Empty results are the same envelope with count 0.
+func structuredResult[T any](results []T, extra any) (*mcp.CallToolResult, error) {
+	if results == nil {
+		results = []T{}
+	}
+	out, err := json.Marshal(struct {
+		Results []T `json:"results"`
+		Count   int `json:"count"`
+	}{results, len(results)})
+	if err == nil && extra != nil {
+		var fields []byte
+		if fields, err = json.Marshal(extra); err == nil && len(fields) > len("{}") {
+			out = append(append(out[:len(out)-1], ','), fields[1:]...)
+		}
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// memoryFilter narrows list and search results to an exact status, to memories carrying
+// every one of the given tags, and to a created_at range.
+type memoryFilter struct {
+	Status string
+	// Statuses, when non-empty, matches memories having any one of these statuses.
+	Statuses []string
+	Tags     []string
+	// ExcludeTags drops memories carrying any of these tags; Untagged keeps only memories
+	// without tags.
+	ExcludeTags []string
+	Untagged    bool
+	// Since and Until are normalized createdAtFormat timestamps; Until is exclusive.
+	Since string
+	Until string
+}
+
+// createdAtFormat is the layout SQLite's strftime('%Y-%m-%dT%H:%M:%fZ') writes created_at in,
+// so timestamps in this layout compare correctly as strings.
+const createdAtFormat = "2006-01-02T15:04:05.000Z"
+
+// parseTimestamp parses an ISO-8601 timestamp (RFC 3339, or a bare date meaning midnight UTC)
+// into createdAtFormat.
+func parseTimestamp(value string) (string, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC().Format(createdAtFormat), nil
+		}
+	}
+	return "", fmt.Errorf("expected an ISO-8601 timestamp such as 2024-06-07T12:00:00Z or 2024-06-07")
+}
+
+// filterFromRequest reads the optional status, statuses, tags, exclude_tags, untagged, since,
+// and until filter params.
+func filterFromRequest(req mcp.CallToolRequest) (memoryFilter, error) {
+	f := memoryFilter{
+		Status:      strings.TrimSpace(req.GetString("status", "")),
+		Tags:        splitTags(req.GetString("tags", "")),
+		ExcludeTags: splitTags(req.GetString("exclude_tags", "")),
+		Untagged:    req.GetBool("untagged", false),
+	}
+	if f.Untagged && len(f.Tags) > 0 {
+		return f, fmt.Errorf("untagged cannot be combined with tags")
+	}
+	for _, status := range req.GetStringSlice("statuses", nil) {
+		if status = strings.TrimSpace(status); status != "" {
+			f.Statuses = append(f.Statuses, status)
+		}
+	}
+	for _, p := range []struct {
+		param string
+		dst   *string
+	}{
+		{"since", &f.Since},
+		{"until", &f.Until},
+	} {
+		v := strings.TrimSpace(req.GetString(p.param, ""))
+		if v == "" {
+			continue
+		}
+		ts, err := parseTimestamp(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid %s %q: %w", p.param, v, err)
+		}
+		*p.dst = ts
+	}
+	// until is exclusive, so a range that does not end after it starts can match nothing.
+	if f.Since != "" && f.Until != "" && f.Since >= f.Until {
+		return f, fmt.Errorf("since %s must be before until %s", f.Since, f.Until)
+	}
+	return f, nil
+}
+
+// tagListSQL is m.tags as ",tag1,tag2,", so a whole tag can be matched with LIKE '%,tag,%'.
+const tagListSQL = `(',' || REPLACE(REPLACE(COALESCE(m.tags, ''), ', ', ','), ' ,', ',') || ',')`
+
+// conditions returns the filter's SQL conditions, referencing simple_memories as alias m,
+// and their arguments. Tags are compared as whole entries of the comma-separated list,
+// so filtering on "work" does not match "homework".
+func (f memoryFilter) conditions() ([]string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	if f.Status != "" {
+		conds = append(conds, "m.status = ?")
+		args = append(args, f.Status)
+	}
+	if len(f.Statuses) > 0 {
+		conds = append(conds, "m.status IN (?"+strings.Repeat(", ?", len(f.Statuses)-1)+")")
+		for _, status := range f.Statuses {
+			args = append(args, status)
+		}
+	}
+	for _, tag := range f.Tags {
+		conds = append(conds, tagListSQL+` LIKE ? ESCAPE '\'`)
+		args = append(args, "%,"+likeEscape(tag)+",%")
+	}
+	for _, tag := range f.ExcludeTags {
+		conds = append(conds, tagListSQL+` NOT LIKE ? ESCAPE '\'`)
+		args = append(args, "%,"+likeEscape(tag)+",%")
+	}
+	if f.Untagged {
+		conds = append(conds, "TRIM(REPLACE(COALESCE(m.tags, ''), ',', '')) = ''")
+	}
+	if f.Since != "" {
+		conds = append(conds, "m.created_at >= ?")
+		args = append(args, f.Since)
+	}
+	if f.Until != "" {
+		conds = append(conds, "m.created_at < ?")
+		args = append(args, f.Until)
+	}
+	return conds, args
+}
+
+// likeEscape escapes the LIKE wildcards in s for use with ESCAPE '\'.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// whereClause joins conditions with AND into a WHERE clause, or returns "" if there are none.
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conds, " AND ")
+}
+
+// explainResult describes the statement a tool would run, with its bound arguments and
+// SQLite's query plan, without executing it.
+func (s *SimpleMemoryServer) explainResult(sqlQuery string, args []any) (*mcp.CallToolResult, error) {
+	rows, err := s.db.Query("EXPLAIN QUERY PLAN "+sqlQuery, args...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to explain query: %v", err)), nil
+	}
+	defer rows.Close()
+	plan := []string{}
+	for rows.Next() {
+		var (
+			id, parent, notUsed int
+			detail              string
+		)
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to explain query: %v", err)), nil
+		}
+		plan = append(plan, detail)
+	}
+	if err := rows.Err(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to explain query: %v", err)), nil
+	}
+	if args == nil {
+		args = []any{}
+	}
+	out, err := json.Marshal(struct {
+		SQL       string   `json:"sql"`
+		Args      []any    `json:"args"`
+		QueryPlan []string `json:"query_plan"`
+	}{
+		SQL:       strings.Join(strings.Fields(sqlQuery), " "),
+		Args:      args,
+		QueryPlan: plan,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode explanation: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// memoryPage is one page of simple_memory_list results along with the total row count.
+type memoryPage struct {
+	Memories []Memory `json:"memories"`
+	Total    int64    `json:"total"`
+	Limit    int      `json:"limit"`
+	Offset   int      `json:"offset"`
+}
+
+// listOrders maps each SIMPLE_MEMORY_DEFAULT_ORDER scheme to the ORDER BY clause of
+// simple_memory_list. IDs break ties so pages never overlap.
+var listOrders = map[string]string{
+	"oldest": "m.id ASC",
+	"recent": "m.created_at DESC, m.id DESC",
+}
+
+// SimpleMemoryList returns a page of simple-memories as JSON, with the total count so callers
+// know whether more remain. A limit of 0 or less returns every memory from offset onwards.
+func (s *SimpleMemoryServer) SimpleMemoryList(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	limit := req.GetInt("limit", defaultListLimit)
+	if limit < 0 {
+		limit = 0
+	}
+	offset := req.GetInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	conds, args := filter.conditions()
+	where := whereClause(conds)
+	// SQLite treats a negative LIMIT as "no limit".
+	sqlLimit := limit
+	if sqlLimit == 0 {
+		sqlLimit = -1
+	}
+	listQuery := "SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT) FROM " + s.view + " m " + where + " ORDER BY " + listOrders[s.listOrder] + " LIMIT ? OFFSET ?"
+	listArgs := append(args, sqlLimit, offset)
+	if req.GetBool("explain", false) {
+		return s.explainResult(listQuery, listArgs)
+	}
+	var total int64
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM "+s.view+" m "+where, args...).Scan(&total); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to count simple-memories: %v", err)), nil
+	}
+	rows, err := s.db.Query(listQuery, listArgs...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	if memories == nil {
+		memories = []Memory{}
+	}
+	if s.structuredResults {
+		return structuredResult(memories, struct {
+			Total  int64 `json:"total"`
+			Limit  int   `json:"limit"`
+			Offset int   `json:"offset"`
+		}{total, limit, offset})
+	}
+	out, err := json.Marshal(memoryPage{Memories: memories, Total: total, Limit: limit, Offset: offset})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode simple-memories: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// queryMatch returns the join, condition, and arguments that restrict simple_memories
+// (alias m) to rows matching query: every term via FTS5 when available, otherwise the
+// whole query as a substring of any field.
+func (s *SimpleMemoryServer) queryMatch(query string) (string, string, []any) {
+	if s.fts {
+		return "JOIN " + s.ftsTable + " ON " + s.ftsTable + ".rowid = m.id",
+			s.ftsTable + " MATCH ?",
+			[]any{ftsMatchExpr(query)}
+	}
+	pattern := s.likePattern(query)
+	return "",
+		"(" + s.likeKey("m.title") + " LIKE ? OR " + s.likeKey("m.tags") + " LIKE ? OR " +
+			s.likeKey("m.status") + " LIKE ? OR " + s.likeKey("m.content") + " LIKE ?)",
+		[]any{pattern, pattern, pattern, pattern}
+}
+
+// matchSQL returns the join, conditions, and arguments that select the memories (alias m)
+// matching query, unless it is empty, and filter.
+func (s *SimpleMemoryServer) matchSQL(query string, filter memoryFilter) (string, []string, []any) {
+	conds, args := filter.conditions()
+	if query == "" {
+		return "", conds, args
+	}
+	join, match, matchArgs := s.queryMatch(query)
+	return join, append([]string{match}, conds...), append(matchArgs, args...)
+}
+
+// likeKey returns the SQL expression a column is LIKE-matched on: the column itself, or
+// its Unicode case fold when unicodeFold is on, since SQLite's LIKE only folds ASCII.
+func (s *SimpleMemoryServer) likeKey(column string) string {
+	if s.unicodeFold {
+		return "simple_memory_fold(" + column + ")"
+	}
+	return column
+}
+
+// likePattern returns the LIKE pattern matching query as a substring of a likeKey.
+func (s *SimpleMemoryServer) likePattern(query string) string {
+	if s.unicodeFold {
+		query = foldCase(query)
+	}
+	return "%" + query + "%"
+}
+
+// searchSQL builds the search statement and its arguments. With FTS5 results are ordered
+// by weighted bm25; otherwise by the sum of the weights of the matching fields. The filter
+// further narrows the matches.
+func (s *SimpleMemoryServer) searchSQL(query string, filter memoryFilter) (string, []any) {
+	join, match, args := s.queryMatch(query)
+	conds, filterArgs := filter.conditions()
+	conds = append([]string{match}, conds...)
+	args = append(args, filterArgs...)
+	var orderBy string
+	if s.fts {
+		orderBy = "bm25(" + s.ftsTable + ", ?, ?, ?, ?) ASC, m.id ASC"
+		args = append(args, s.weights.Title, s.weights.Tags, s.weights.Status, s.weights.Content)
+	} else {
+		orderBy = `(
+			CASE WHEN ` + s.likeKey("m.title") + ` LIKE ? THEN ? ELSE 0 END +
+			CASE WHEN ` + s.likeKey("m.tags") + ` LIKE ? THEN ? ELSE 0 END +
+			CASE WHEN ` + s.likeKey("m.status") + ` LIKE ? THEN ? ELSE 0 END +
+			CASE WHEN ` + s.likeKey("m.content") + ` LIKE ? THEN ? ELSE 0 END
+		) DESC, m.id ASC`
+		pattern := s.likePattern(query)
+		args = append(args,
+			pattern, s.weights.Title,
+			pattern, s.weights.Tags,
+			pattern, s.weights.Status,
+			pattern, s.weights.Content,
+		)
+	}
+	return `
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+		FROM ` + s.view + ` m
+		` + join + `
+		` + whereClause(conds) + `
+		ORDER BY ` + orderBy, args
+}
+
+// SimpleMemorySearch returns a JSON array of simple-memories matching query in title, tags, status, or content,
+// ranked by relevance using the configured per-field weights (ties broken by id).
+func (s *SimpleMemoryServer) SimpleMemorySearch(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	queryParam, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	query := strings.TrimSpace(queryParam)
+	if query == "" {
+		return mcp.NewToolResultError("query cannot be empty"), nil
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	sqlQuery, args := s.searchSQL(query, filter)
+	if req.GetBool("explain", false) {
+		return s.explainResult(sqlQuery, args)
+	}
+	rows, err := s.db.Query(sqlQuery, args...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to search simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	matches, err := scanMemories(rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to search simple-memories: %v", err)), nil
+	}
+	if s.structuredResults {
+		return structuredResult(matches, nil)
+	}
+	if len(matches) == 0 {
+		return mcp.NewToolResultText("No matching simple-memories found."), nil
+	}
+	return memoriesResult(matches)
+}
+
+// SimpleMemoryCount returns the number of simple-memories, optionally restricted to those
+// matching a query and filters, with a per-status breakdown.
+func (s *SimpleMemoryServer) SimpleMemoryCount(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	join, conds, args := s.matchSQL(strings.TrimSpace(req.GetString("query", "")), filter)
+	rows, err := s.db.Query(`
+		SELECT COALESCE(NULLIF(TRIM(m.status), ''), '(none)') AS status_key, COUNT(*)
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds)+`
+		GROUP BY status_key
+		ORDER BY status_key ASC
+	`, args...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to count simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	counts := struct {
+		Total    int64            `json:"total"`
+		ByStatus map[string]int64 `json:"by_status"`
+		Health   *HealthStatus    `json:"health,omitempty"`
+	}{ByStatus: map[string]int64{}, Health: s.healthStatus()}
+	for rows.Next() {
+		var (
+			status string
+			n      int64
+		)
+		if err := rows.Scan(&status, &n); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to count simple-memories: %v", err)), nil
+		}
+		counts.ByStatus[status] = n
+		counts.Total += n
+	}
+	if err := rows.Err(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to count simple-memories: %v", err)), nil
+	}
+	if s.structuredResults {
+		type statusCount struct {
+			Status string `json:"status"`
+			Count  int64  `json:"count"`
+		}
+		results := []statusCount{}
+		for _, status := range slices.Sorted(maps.Keys(counts.ByStatus)) {
+			results = append(results, statusCount{status, counts.ByStatus[status]})
+		}
+		return structuredResult(results, struct {
+			Total  int64         `json:"total"`
+			Health *HealthStatus `json:"health,omitempty"`
+		}{counts.Total, counts.Health})
+	}
+	out, err := json.Marshal(counts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode counts: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// SimpleMemoryFilterIDs returns the memories among the given IDs that also match the optional
+// query and filters, in the order the IDs were given.
+func (s *SimpleMemoryServer) SimpleMemoryFilterIDs(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	ids, err := req.RequireIntSlice("ids")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	rank := make(map[int64]int, len(ids))
+	idArgs := make([]any, 0, len(ids))
+	for _, id := range ids {
+		if _, dup := rank[int64(id)]; !dup {
+			rank[int64(id)] = len(rank)
+			idArgs = append(idArgs, id)
+		}
+	}
+	if len(idArgs) == 0 {
+		if s.structuredResults {
+			return structuredResult([]Memory{}, nil)
+		}
+		return mcp.NewToolResultText("No matching simple-memories found."), nil
+	}
+	join, conds, args := s.matchSQL(strings.TrimSpace(req.GetString("query", "")), filter)
+	conds = append(conds, "m.id IN (?"+strings.Repeat(", ?", len(idArgs)-1)+")")
+	rows, err := s.db.Query(`
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds), append(args, idArgs...)...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	sort.Slice(memories, func(i, j int) bool { return rank[memories[i].ID] < rank[memories[j].ID] })
+	if s.structuredResults {
+		return structuredResult(memories, nil)
+	}
+	if len(memories) == 0 {
+		return mcp.NewToolResultText("No matching simple-memories found."), nil
+	}
+	return memoriesResult(memories)
+}
+
+// defaultExtremesLimit is how many memories simple_memory_extremes lists at each end by default.
+const defaultExtremesLimit = 5
+
+// SimpleMemoryExtremes returns the longest and shortest simple-memories by content length in
+// characters, optionally restricted to a query and filters.
+func (s *SimpleMemoryServer) SimpleMemoryExtremes(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	limit := req.GetInt("limit", defaultExtremesLimit)
+	if limit <= 0 {
+		return mcp.NewToolResultError("invalid params: limit must be positive"), nil
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	join, conds, args := s.matchSQL(strings.TrimSpace(req.GetString("query", "")), filter)
+	type sizedMemory struct {
+		Memory
+		Length int `json:"length"`
+	}
+	result := struct {
+		Longest  []sizedMemory `json:"longest"`
+		Shortest []sizedMemory `json:"shortest"`
+	}{}
+	for _, end := range []struct {
+		order string
+		dst   *[]sizedMemory
+	}{
+		{"DESC", &result.Longest},
+		{"ASC", &result.Shortest},
+	} {
+		rows, err := s.db.Query(`
+			SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+			FROM `+s.view+` m
+			`+join+`
+			`+whereClause(conds)+`
+			ORDER BY LENGTH(m.content) `+end.order+`, m.id ASC
+			LIMIT ?
+		`, append(args, limit)...)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+		}
+		memories, err := scanMemories(rows)
+		rows.Close()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+		}
+		*end.dst = []sizedMemory{}
+		for _, m := range memories {
+			*end.dst = append(*end.dst, sizedMemory{Memory: m, Length: utf8.RuneCountInString(m.Content)})
+		}
+	}
+	if s.structuredResults {
+		// One flat list, each memory marked with the end it was found at.
+		type endMemory struct {
+			End string `json:"end"`
+			sizedMemory
+		}
+		results := []endMemory{}
+		for _, m := range result.Longest {
+			results = append(results, endMemory{"longest", m})
+		}
+		for _, m := range result.Shortest {
+			results = append(results, endMemory{"shortest", m})
+		}
+		return structuredResult(results, nil)
+	}
+	if len(result.Longest) == 0 {
+		return mcp.NewToolResultText("No matching simple-memories found."), nil
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// deleteToken is an outstanding delete confirmation: the delete it was issued for, the IDs that
+// delete would remove, and when it lapses.
+type deleteToken struct {
+	target  string
+	ids     []int64
+	expires time.Time
+}
+
+// confirmedDelete deletes the memories selected by where and args in a transaction and returns
+// the IDs it deleted. When delete confirmation is enabled the first call deletes nothing and
+// returns a result holding a confirm_token for target; the delete only happens once the same
+// token is passed back before it expires and the same memories still match.
+func (s *SimpleMemoryServer) confirmedDelete(req mcp.CallToolRequest, target, where string, args ...any) ([]int64, *mcp.CallToolResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	rows, err := tx.Query("SELECT id FROM "+s.table+" "+where+" ORDER BY id ASC", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if s.deleteConfirmTTL > 0 && len(ids) > 0 {
+		if result := s.checkDeleteToken(req.GetString("confirm_token", ""), target, ids); result != nil {
+			return nil, result, nil
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM "+s.table+" "+where, args...); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return ids, nil, nil
+}
+
+// checkDeleteToken returns nil if token confirms deleting ids for target, consuming it.
+// Otherwise it returns the result to send instead: a new token when none was given, or an
+// error when the token is unknown, expired, or was issued for a different delete.
+func (s *SimpleMemoryServer) checkDeleteToken(token, target string, ids []int64) *mcp.CallToolResult {
+	now := time.Now()
+	s.deleteTokensMu.Lock()
+	defer s.deleteTokensMu.Unlock()
+	for t, pending := range s.deleteTokens {
+		if !now.Before(pending.expires) {
+			delete(s.deleteTokens, t)
+		}
+	}
+	if token = strings.TrimSpace(token); token != "" {
+		pending, ok := s.deleteTokens[token]
+		delete(s.deleteTokens, token)
+		switch {
+		case !ok:
+			return mcp.NewToolResultError("confirm_token is unknown, expired, or already used; call again without it for a new token")
+		case pending.target != target || !slices.Equal(pending.ids, ids):
+			return mcp.NewToolResultError(fmt.Sprintf("confirm_token does not match this delete (it was issued for %s, matching ids %v); call again without it for a new token", pending.target, pending.ids))
+		}
+		return nil
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to issue confirm_token: %v", err))
+	}
+	pending := deleteToken{target: target, ids: ids, expires: now.Add(s.deleteConfirmTTL)}
+	token = hex.EncodeToString(b[:])
+	s.deleteTokens[token] = pending
+	out, err := json.Marshal(struct {
+		ConfirmToken string  `json:"confirm_token"`
+		ExpiresAt    string  `json:"expires_at"`
+		Summary      string  `json:"summary"`
+		IDs          []int64 `json:"ids"`
+	}{
+		ConfirmToken: token,
+		ExpiresAt:    pending.expires.UTC().Format(createdAtFormat),
+		Summary:      fmt.Sprintf("Will delete %d simple-memories matching %s. Call again with confirm_token to proceed.", len(ids), target),
+		IDs:          ids,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode confirm_token: %v", err))
+	}
+	return mcp.NewToolResultText(string(out))
+}
+
+// SimpleMemoryDelete deletes all simple-memories containing the query substring.
+func (s *SimpleMemoryServer) SimpleMemoryDelete(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	queryParam, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	query := strings.TrimSpace(queryParam)
+	if query == "" {
+		return mcp.NewToolResultError("query cannot be empty"), nil
+	}
+	where := `
+		WHERE ` + s.likeKey("title") + ` LIKE ? OR ` + s.likeKey("tags") + ` LIKE ? OR ` +
+		s.likeKey("status") + ` LIKE ? OR ` + s.likeKey("content") + ` LIKE ?
+	`
+	pattern := s.likePattern(query)
+	ids, result, err := s.confirmedDelete(req, "query "+strconv.Quote(query), where, pattern, pattern, pattern, pattern)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to delete simple-memories: %v", err)), nil
+	}
+	if result != nil {
+		return result, nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Deleted %d simple-memories matching %q in any field", len(ids), query)
+	}
+	if s.structuredResults {
+		return structuredResult(ids, nil)
+	}
+	if len(ids) == 0 {
+		return mcp.NewToolResultText("No simple-memories deleted (no match)."), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Deleted %d simple-memories.", len(ids))), nil
+}
+
+// SimpleMemoryDeleteByID deletes the single simple-memory with the given ID.
+func (s *SimpleMemoryServer) SimpleMemoryDeleteByID(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireInt("id")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	ids, result, err := s.confirmedDelete(req, fmt.Sprintf("id %d", id), "WHERE id = ?", id)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to delete simple-memory: %v", err)), nil
+	}
+	if result != nil {
+		return result, nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Deleted %d simple-memories with id %d", len(ids), id)
+	}
+	if s.structuredResults {
+		return structuredResult(ids, nil)
+	}
+	if len(ids) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No memory found with id %d.", id)), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Deleted memory %d.", id)), nil
+}
+
+// resolvePath returns the absolute form of path with symlinks followed as far as the path
+// exists, so that a link cannot make a path look like it is somewhere it is not.
+func resolvePath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(path)}, missing...)
+		path = parent
+	}
+}
+
+// toolPath resolves the path parameter of an import or export tool, relative to s.fileDir,
+// to an absolute path. It rejects paths that resolve outside s.fileDir and file extensions
+// outside s.pathExtensions. A directory, where dirOK, skips the extension check since its
+// entries are filtered by the caller.
+func (s *SimpleMemoryServer) toolPath(pathParam string, dirOK bool) (string, error) {
+	if s.fileDir == "" {
+		return "", errors.New("file access is not configured (set SIMPLE_MEMORY_FILE_DIR)")
+	}
+	pathParam = strings.TrimSpace(pathParam)
+	if pathParam == "" {
+		return "", errors.New("path cannot be empty")
+	}
+	path := pathParam
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.fileDir, path)
+	}
+	path, err := resolvePath(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid path %q: %v", pathParam, err)
+	}
+	rel, err := filepath.Rel(s.fileDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path %q: must be inside %s", pathParam, s.fileDir)
+	}
+	if dirOK {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path, nil
+		}
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); !slices.Contains(s.pathExtensions, ext) {
+		return "", fmt.Errorf("invalid path %q: extension %q is not allowed, must be one of %s", pathParam, ext, strings.Join(s.pathExtensions, ", "))
+	}
+	return path, nil
+}
+
+// writeJSONFile atomically writes v as indented JSON to path, refusing to overwrite an existing file.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeNewFile(path, data)
+}
+
+// writeNewFile atomically writes data to path, refusing to overwrite an existing file.
+func writeNewFile(path string, data []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("file %s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".simple-memory-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SimpleMemoryExportDB copies the simple-memories matching a query and filters into a new
+// SQLite database file with the same schema, keeping their IDs and timestamps. The file is
+// built under a temporary name and only moved into place once complete.
+func (s *SimpleMemoryServer) SimpleMemoryExportDB(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	pathParam, err := req.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	path, err := s.toolPath(pathParam, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("file %s already exists", path)), nil
+	} else if !os.IsNotExist(err) {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to check %s: %v", path, err)), nil
+	}
+	join, conds, args := s.matchSQL(strings.TrimSpace(req.GetString("query", "")), filter)
+	n, err := s.copyToNewDB(path, join, conds, args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to export simple-memories to %s: %v", path, err)), nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Exported %d simple-memories to database %q", n, path)
+	}
+	out, err := json.Marshal(struct {
+		Path  string `json:"path"`
+		Count int64  `json:"count"`
+	}{path, n})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode result: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// copyToNewDB creates a database at path holding only the memories selected by join, conds,
+// and args, and returns how many were copied.
+func (s *SimpleMemoryServer) copyToNewDB(path, join string, conds []string, args []any) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".simple-memory-export-*.db")
+	if err != nil {
+		return 0, err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+	dst, err := sql.Open(DriverName, tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+	if err := migrateSchema(dst, s.table, 0); err != nil {
+		return 0, err
+	}
+	if s.fts {
+		if err := setupFTS(dst, s.table); err != nil {
+			return 0, fmt.Errorf("failed to set up full-text index: %w", err)
+		}
+	}
+
+	src, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = src.Rollback() }()
+	tx, err := dst.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	const columns = "id, title, tags, status, content, created_at, remind_at, reminder_acked_at"
+	// created_at is cast so the driver returns the stored text rather than a reformatted time.
+	rows, err := src.Query(`
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT), m.remind_at, m.reminder_acked_at
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds)+`
+		ORDER BY m.id ASC
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	stmt, err := tx.Prepare("INSERT INTO " + s.table + " (" + columns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	var n int64
+	for rows.Next() {
+		values := make([]any, 8)
+		ptrs := make([]any, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return 0, err
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	if err := dst.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// archivedMemory is a memory as simple_memory_archive_and_clear writes it, with its reminder
+// state, which is cleared along with the row.
+type archivedMemory struct {
+	Memory
+	RemindAt        *string `json:"remind_at"`
+	ReminderAckedAt *string `json:"reminder_acked_at"`
+}
+
+// SimpleMemoryArchiveAndClear exports every simple-memory to a JSON file and, only once the
+// export has been written, deletes the exported rows.
+func (s *SimpleMemoryServer) SimpleMemoryArchiveAndClear(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	pathParam, err := req.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	if !req.GetBool("confirm", false) {
+		return mcp.NewToolResultError("refusing to clear simple-memories without confirm: true"), nil
+	}
+	path, err := s.toolPath(pathParam, false)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start transaction: %v", err)), nil
+	}
+	defer func() { _ = tx.Rollback() }()
+	rows, err := tx.Query("SELECT id, title, tags, status, content, CAST(created_at AS TEXT), remind_at, reminder_acked_at FROM " + s.view + " ORDER BY id ASC")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	memories := []archivedMemory{}
+	for rows.Next() {
+		var (
+			m                                       archivedMemory
+			title, tags, status, content, createdAt sql.NullString
+		)
+		if err := rows.Scan(&m.ID, &title, &tags, &status, &content, &createdAt, &m.RemindAt, &m.ReminderAckedAt); err != nil {
+			rows.Close()
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories, nothing was cleared: %v", err)), nil
+		}
+		// Rows scanMemories would skip are neither archived nor cleared.
+		if strings.TrimSpace(content.String) == "" {
+			continue
+		}
+		m.Title, m.Tags, m.Status = title.String, tags.String, status.String
+		m.Content, m.CreatedAt = content.String, createdAt.String
+		memories = append(memories, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	if err := writeJSONFile(path, memories); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to export simple-memories, nothing was cleared: %v", err)), nil
+	}
+	// Delete exactly the exported rows so nothing that missed the archive is lost.
+	stmt, err := tx.Prepare("DELETE FROM " + s.table + " WHERE id = ?")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("exported to %s but failed to clear simple-memories: %v", path, err)), nil
+	}
+	defer stmt.Close()
+	for _, m := range memories {
+		if _, err := stmt.Exec(m.ID); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("exported to %s but failed to clear simple-memories: %v", path, err)), nil
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("exported to %s but failed to clear simple-memories: %v", path, err)), nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Archived %d simple-memories to %q and cleared them", len(memories), path)
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Archived %d simple-memories to %s and cleared them.", len(memories), path)), nil
+}
+
+// archivedStatus is the status given to memories folded into a digest.
+const archivedStatus = "archived"
+
+// composeDigest concatenates memories, oldest first, into the content of a digest memory.
+// Each section is headed by the ID (and title, if any) of the memory it came from.
+func composeDigest(memories []Memory) string {
+	ids := make([]string, len(memories))
+	for i, m := range memories {
+		ids[i] = "#" + strconv.FormatInt(m.ID, 10)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Digest of %d simple-memories: %s", len(memories), strings.Join(ids, ", "))
+	for _, m := range memories {
+		fmt.Fprintf(&b, "\n\n[#%d]", m.ID)
+		if m.Title != "" {
+			b.WriteString(" " + m.Title)
+		}
+		b.WriteString("\n" + m.Content)
+	}
+	return b.String()
+}
+
+// SimpleMemoryDigest concatenates the simple-memories matching a query and/or tags into a
+// single new digest memory, optionally marking the originals as archived. With dry_run the
+// digest is composed and returned without changing anything.
+func (s *SimpleMemoryServer) SimpleMemoryDigest(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query := strings.TrimSpace(req.GetString("query", ""))
+	filter := memoryFilter{Tags: splitTags(req.GetString("tags", ""))}
+	if query == "" && len(filter.Tags) == 0 {
+		return mcp.NewToolResultError("invalid params: query or tags is required"), nil
+	}
+	archive := req.GetBool("archive", false)
+	dryRun := req.GetBool("dry_run", false)
+
+	join, conds, args := s.matchSQL(query, filter)
+	// Memories already folded into an earlier digest are not digested again.
+	conds = append(conds, "COALESCE(m.status, '') != ?")
+	args = append(args, archivedStatus)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start transaction: %v", err)), nil
+	}
+	defer func() { _ = tx.Rollback() }()
+	rows, err := tx.Query(`
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds)+`
+		ORDER BY m.id ASC
+	`, args...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	memories, err := scanMemories(rows)
+	rows.Close()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	if len(memories) == 0 {
+		if s.structuredResults {
+			return structuredResult([]Memory{}, nil)
+		}
+		return mcp.NewToolResultText("No matching simple-memories found."), nil
+	}
+
+	digest := Memory{
+		Title:   strings.TrimSpace(req.GetString("title", "")),
+		Tags:    mergeTags(strings.Join(append(filter.Tags, "digest"), ", "), s.contextTags),
+		Content: composeDigest(memories),
+	}
+	if digest.Title == "" {
+		digest.Title = "Digest: " + strings.Join(append([]string{query}, filter.Tags...), " ")
+		digest.Title = strings.TrimSpace(strings.Join(strings.Fields(digest.Title), " "))
+	}
+	// The digest is a new memory, so it is held to the same limits as one added directly.
+	if err := s.checkContentLength(digest.Content); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("digest of %d simple-memories is invalid, nothing was changed: %v", len(memories), err)), nil
+	}
+	sourceIDs := make([]int64, len(memories))
+	for i, m := range memories {
+		sourceIDs[i] = m.ID
+	}
+	result := struct {
+		DryRun    bool    `json:"dry_run"`
+		Digest    Memory  `json:"digest"`
+		SourceIDs []int64 `json:"source_ids"`
+		Archived  int     `json:"archived"`
+	}{DryRun: dryRun, SourceIDs: sourceIDs}
+
+	if !dryRun {
+		res, err := tx.Exec(
+			"INSERT INTO "+s.table+" (title, tags, status, content) VALUES (?, ?, '', ?)",
+			digest.Title, digest.Tags, digest.Content,
+		)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add digest: %v", err)), nil
+		}
+		if digest.ID, err = res.LastInsertId(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add digest: %v", err)), nil
+		}
+		if err := tx.QueryRow("SELECT CAST(created_at AS TEXT) FROM "+s.table+" WHERE id = ?", digest.ID).Scan(&digest.CreatedAt); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add digest: %v", err)), nil
+		}
+		if archive {
+			stmt, err := tx.Prepare("UPDATE " + s.table + " SET status = ? WHERE id = ?")
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to archive digested simple-memories: %v", err)), nil
+			}
+			defer stmt.Close()
+			for _, id := range sourceIDs {
+				if _, err := stmt.Exec(archivedStatus, id); err != nil {
+					return mcp.NewToolResultError(fmt.Sprintf("failed to archive digested simple-memories: %v", err)), nil
+				}
+			}
+			result.Archived = len(sourceIDs)
+		}
+		if err := tx.Commit(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to add digest: %v", err)), nil
+		}
+		if !s.disableLogging {
+			s.logger.Printf("[INFO] Added digest %d of %d simple-memories (archived %d)", digest.ID, len(sourceIDs), result.Archived)
+		}
+	}
+	result.Digest = digest
+	if s.structuredResults {
+		return structuredResult([]Memory{digest}, struct {
+			DryRun    bool    `json:"dry_run"`
+			SourceIDs []int64 `json:"source_ids"`
+			Archived  int     `json:"archived"`
+		}{result.DryRun, result.SourceIDs, result.Archived})
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode digest: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// defaultTransformSample is how many before/after pairs simple_memory_transform shows by default.
+const defaultTransformSample = 3
+
+// SimpleMemoryTransform rewrites the content of every simple-memory matching a query and/or
+// tags, either by rendering a Go template with the memory as data or by a literal find and
+// replace. All changes are made in one transaction; with dry_run nothing is written and only
+// the preview and counts are returned.
+func (s *SimpleMemoryServer) SimpleMemoryTransform(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query := strings.TrimSpace(req.GetString("query", ""))
+	filter := memoryFilter{Tags: splitTags(req.GetString("tags", ""))}
+	if query == "" && len(filter.Tags) == 0 {
+		return mcp.NewToolResultError("invalid params: query or tags is required"), nil
+	}
+	args := req.GetArguments()
+	_, hasFind := args["find"]
+	tmplSrc := req.GetString("template", "")
+	find := req.GetString("find", "")
+	replace := req.GetString("replace", "")
+	var transform func(Memory) (string, error)
+	switch {
+	case tmplSrc != "" && hasFind:
+		return mcp.NewToolResultError("invalid params: give either template or find/replace, not both"), nil
+	case tmplSrc != "":
+		tmpl, err := template.New("transform").Option("missingkey=error").Parse(tmplSrc)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid params: failed to parse template: %v", err)), nil
+		}
+		transform = func(m Memory) (string, error) {
+			var out strings.Builder
+			if err := tmpl.Execute(&out, m); err != nil {
+				return "", err
+			}
+			return out.String(), nil
+		}
+	case find != "":
+		transform = func(m Memory) (string, error) { return strings.ReplaceAll(m.Content, find, replace), nil }
+	default:
+		return mcp.NewToolResultError("invalid params: template or a non-empty find is required"), nil
+	}
+	dryRun := req.GetBool("dry_run", false)
+	sample := req.GetInt("sample", defaultTransformSample)
+
+	join, conds, sqlArgs := s.matchSQL(query, filter)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start transaction: %v", err)), nil
+	}
+	defer func() { _ = tx.Rollback() }()
+	rows, err := tx.Query(`
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds)+`
+		ORDER BY m.id ASC
+	`, sqlArgs...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	memories, err := scanMemories(rows)
+	rows.Close()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+
+	type change struct {
+		ID     int64  `json:"id"`
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}
+	result := struct {
+		DryRun  bool     `json:"dry_run"`
+		Matched int      `json:"matched"`
+		Changed int      `json:"changed"`
+		Preview []change `json:"preview"`
+	}{DryRun: dryRun, Matched: len(memories), Preview: []change{}}
+	stmt, err := tx.Prepare("UPDATE " + s.table + " SET content = ? WHERE id = ?")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to transform simple-memories: %v", err)), nil
+	}
+	defer stmt.Close()
+	for _, m := range memories {
+		out, err := transform(m)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to transform memory %d, nothing was changed: %v", m.ID, err)), nil
+		}
+		content := s.trimField("content", out)
+		if content == m.Content {
+			continue
+		}
+		if strings.TrimSpace(content) == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("transform would leave memory %d empty, nothing was changed", m.ID)), nil
+		}
+		if err := s.checkContentLength(content); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("transformed memory %d is invalid, nothing was changed: %v", m.ID, err)), nil
+		}
+		if len(result.Preview) < sample {
+			result.Preview = append(result.Preview, change{ID: m.ID, Before: m.Content, After: content})
+		}
+		result.Changed++
+		if dryRun {
+			continue
+		}
+		if _, err := stmt.Exec(content, m.ID); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to transform memory %d, nothing was changed: %v", m.ID, err)), nil
+		}
+	}
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to transform simple-memories: %v", err)), nil
+		}
+		if !s.disableLogging {
+			s.logger.Printf("[INFO] Transformed %d of %d matching simple-memories", result.Changed, result.Matched)
+		}
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// SimpleMemoryTagMatches adds tags to every simple-memory matching a query and filters. The
+// matching IDs are captured and tagged in one transaction, so rows that start matching
+// while it runs are left alone. Passing the ids from a dry run limits the update to the
+// memories that were previewed, even if more match by the time it is applied.
+func (s *SimpleMemoryServer) SimpleMemoryTagMatches(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	if query = strings.TrimSpace(query); query == "" {
+		return mcp.NewToolResultError("query cannot be empty"), nil
+	}
+	addTags := splitTags(req.GetString("add_tags", ""))
+	if len(addTags) == 0 {
+		return mcp.NewToolResultError("invalid params: add_tags must name at least one tag"), nil
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	dryRun := req.GetBool("dry_run", false)
+
+	join, conds, args := s.matchSQL(query, filter)
+	if _, ok := req.GetArguments()["ids"]; ok {
+		// An unusable ids list must not widen the update to every match.
+		ids := req.GetIntSlice("ids", nil)
+		if len(ids) == 0 {
+			return mcp.NewToolResultError("invalid params: ids must list at least one memory ID"), nil
+		}
+		conds = append(conds, "m.id IN (?"+strings.Repeat(", ?", len(ids)-1)+")")
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start transaction: %v", err)), nil
+	}
+	defer func() { _ = tx.Rollback() }()
+	rows, err := tx.Query(`
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds)+`
+		ORDER BY m.id ASC
+	`, args...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	memories, err := scanMemories(rows)
+	rows.Close()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+
+	result := struct {
+		DryRun  bool    `json:"dry_run"`
+		Matched int     `json:"matched"`
+		Changed int     `json:"changed"`
+		IDs     []int64 `json:"ids"`
+	}{DryRun: dryRun, Matched: len(memories), IDs: []int64{}}
+	stmt, err := tx.Prepare("UPDATE " + s.table + " SET tags = ? WHERE id = ?")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to tag simple-memories: %v", err)), nil
+	}
+	defer stmt.Close()
+	for _, m := range memories {
+		result.IDs = append(result.IDs, m.ID)
+		tags := mergeTags(m.Tags, addTags)
+		if strings.EqualFold(strings.Join(splitTags(tags), ","), strings.Join(splitTags(m.Tags), ",")) {
+			continue
+		}
+		result.Changed++
+		if dryRun {
+			continue
+		}
+		if _, err := stmt.Exec(tags, m.ID); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to tag memory %d, nothing was changed: %v", m.ID, err)), nil
+		}
+	}
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to tag simple-memories: %v", err)), nil
+		}
+		if !s.disableLogging {
+			s.logger.Printf("[INFO] Tagged %d of %d simple-memories matching %q with %q", result.Changed, result.Matched, query, strings.Join(addTags, ","))
+		}
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// SimpleMemoryExport returns every simple-memory, with its ID and created_at, as a JSON
+// array that simple_memory_import accepts.
+func (s *SimpleMemoryServer) SimpleMemoryExport(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	memories, err := s.allMemories()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	return memoriesResult(memories)
+}
+
+// allMemories returns every simple-memory in ID order, never nil.
+func (s *SimpleMemoryServer) allMemories() ([]Memory, error) {
+	rows, err := s.db.Query("SELECT id, title, tags, status, content, CAST(created_at AS TEXT) FROM " + s.view + " ORDER BY id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return nil, err
+	}
+	if memories == nil {
+		memories = []Memory{}
+	}
+	return memories, nil
+}
+
+// exportFiles returns the paths of the periodic exports of this table in dir, oldest first.
+func (s *SimpleMemoryServer) exportFiles(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, s.table+"-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// The timestamp in the name sorts chronologically.
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// exportToDir writes every simple-memory to a new timestamped JSON file in dir, in the format
+// simple_memory_export returns, and then deletes all but the newest keep exports (keep 0 keeps
+// them all). Nothing is written when the newest export already holds the same memories, in
+// which case the returned path is empty.
+func (s *SimpleMemoryServer) exportToDir(dir string, keep int) (string, error) {
+	memories, err := s.allMemories()
+	if err != nil {
+		return "", fmt.Errorf("failed to read simple-memories: %w", err)
+	}
+	data, err := json.MarshalIndent(memories, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode simple-memories: %w", err)
+	}
+	paths, err := s.exportFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) > 0 {
+		if last, err := os.ReadFile(paths[len(paths)-1]); err == nil && bytes.Equal(last, data) {
+			return "", nil
+		}
+	}
+	path := filepath.Join(dir, s.table+"-"+time.Now().UTC().Format("20060102T150405.000Z")+".json")
+	if err := writeNewFile(path, data); err != nil {
+		return "", fmt.Errorf("failed to write export: %w", err)
+	}
+	if paths = append(paths, path); keep > 0 && len(paths) > keep {
+		for _, old := range paths[:len(paths)-keep] {
+			if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+				return path, fmt.Errorf("failed to remove old export: %w", err)
+			}
+		}
+	}
+	return path, nil
+}
+
+// WatchExports exports to dir immediately, every interval, and once more when ctx is done, so
+// that the last export reflects the database at shutdown. Unchanged data is not exported again,
+// and only the newest keep exports are kept (0 keeps all).
+func (s *SimpleMemoryServer) WatchExports(ctx context.Context, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for done := false; ; {
+		path, err := s.exportToDir(dir, keep)
+		if !s.disableLogging {
+			switch {
+			case err != nil:
+				s.logger.Printf("[ERROR] Periodic export to %s failed: %v", dir, err)
+			case path != "":
+				s.logger.Printf("[INFO] Exported simple-memories to %s", path)
+			}
+		}
+		if done {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			done = true
+		case <-ticker.C:
+		}
+	}
+}
+
+// importedMemory is one entry of a simple_memory_import payload. IDs are ignored so
+// imported rows never collide with existing ones.
+type importedMemory struct {
+	Title     string `json:"title"`
+	Tags      string `json:"tags"`
+	Status    string `json:"status"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+}
+
+// SimpleMemoryImport inserts the memories of a simple_memory_export payload in a single
+// transaction, keeping their created_at but assigning new IDs. In replace mode all
+// existing memories are deleted first. Any invalid entry rolls back the whole import.
+func (s *SimpleMemoryServer) SimpleMemoryImport(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	raw, ok := req.GetArguments()["memories"]
+	if !ok {
+		return mcp.NewToolResultError("invalid params: required argument \"memories\" not found"), nil
+	}
+	// Accept the export output verbatim as a string, or the decoded array.
+	payload, isString := raw.(string)
+	if !isString {
+		b, err := json.Marshal(raw)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+		}
+		payload = string(b)
+	}
+	var entries []importedMemory
+	if err := json.Unmarshal([]byte(payload), &entries); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: memories must be a JSON array of memories: %v", err)), nil
+	}
+	mode := strings.ToLower(strings.TrimSpace(req.GetString("mode", "append")))
+	if mode != "append" && mode != "replace" {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid mode %q: must be append or replace", mode)), nil
+	}
+	// An empty payload would only clear the store; that goes through simple_memory_delete and its confirmation.
+	if mode == "replace" && len(entries) == 0 {
+		return mcp.NewToolResultError("replace mode needs at least one memory to import, nothing was deleted; use simple_memory_delete to clear simple-memories"), nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start transaction: %v", err)), nil
+	}
+	defer func() { _ = tx.Rollback() }()
+	var replaced int64
+	if mode == "replace" {
+		res, err := tx.Exec("DELETE FROM " + s.table)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to clear simple-memories: %v", err)), nil
+		}
+		replaced, _ = res.RowsAffected()
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO ` + s.table + ` (title, tags, status, content, created_at)
+		VALUES (?, ?, ?, ?, COALESCE(?, strftime('%Y-%m-%dT%H:%M:%fZ', 'now')))
+	`)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to import simple-memories: %v", err)), nil
+	}
+	defer stmt.Close()
+	for i, e := range entries {
+		content := s.trimField("content", e.Content)
+		if strings.TrimSpace(content) == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("memory %d: content cannot be empty, nothing was imported", i)), nil
+		}
+		if err := s.checkContentLength(content); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("memory %d: %v, nothing was imported", i, err)), nil
+		}
+		var createdAt any
+		if v := strings.TrimSpace(e.CreatedAt); v != "" {
+			ts, err := parseTimestamp(v)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("memory %d: invalid created_at %q, nothing was imported: %v", i, v, err)), nil
+			}
+			createdAt = ts
+		}
+		if _, err := stmt.Exec(s.trimField("title", e.Title), s.trimField("tags", e.Tags), s.trimField("status", e.Status), content, createdAt); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("memory %d: failed to import, nothing was imported: %v", i, err)), nil
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to import simple-memories: %v", err)), nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Imported %d simple-memories (mode=%s, replaced %d)", len(entries), mode, replaced)
+	}
+	if mode == "replace" {
+		return mcp.NewToolResultText(fmt.Sprintf("Replaced %d simple-memories with %d imported ones.", replaced, len(entries))), nil
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Imported %d simple-memories.", len(entries))), nil
+}
+
+// markdownFrontMatter is the YAML front matter recognised by simple_memory_import_markdown.
+// Tags may be a comma-separated string or a list.
+type markdownFrontMatter struct {
+	Title  string `yaml:"title"`
+	Tags   any    `yaml:"tags"`
+	Status string `yaml:"status"`
+}
+
+// splitFrontMatter separates a leading "---" delimited YAML block from the Markdown body.
+// Without a complete block the whole text is the body.
+func splitFrontMatter(text string) (string, string) {
+	text = strings.TrimPrefix(strings.ReplaceAll(text, "\r\n", "\n"), "\ufeff")
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text
+	}
+	rest := text[len("---\n"):]
+	for offset := 0; offset < len(rest); {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if line == "---" || line == "..." {
+			if end < 0 {
+				return rest[:offset], ""
+			}
+			return rest[:offset], rest[offset+end+1:]
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return "", text
+}
+
+// markdownSections splits a Markdown body at its level 1 and 2 headings, ignoring lines in
+// fenced code blocks. Text before the first heading is returned with an empty heading.
+func markdownSections(body string) []struct{ heading, text string } {
+	var (
+		sections []struct{ heading, text string }
+		heading  string
+		lines    []string
+		fenced   bool
+	)
+	flush := func() {
+		sections = append(sections, struct{ heading, text string }{heading, strings.TrimSpace(strings.Join(lines, "\n"))})
+	}
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if !fenced && (strings.HasPrefix(line, "# ") || strings.HasPrefix(line, "## ")) {
+			flush()
+			heading, lines = strings.TrimSpace(strings.TrimLeft(line, "#")), nil
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return sections
+}
+
+// parseMarkdownNote turns a Markdown note into memories. Front matter supplies the title,
+// tags and status; a missing title falls back to the file name. With bySection every
+// level 1 or 2 heading starts a new memory titled after the heading. Memories without
+// content are left out.
+func parseMarkdownNote(name, text string, bySection bool) ([]Memory, error) {
+	rawMatter, body := splitFrontMatter(text)
+	var matter markdownFrontMatter
+	if err := yaml.Unmarshal([]byte(rawMatter), &matter); err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+	var tags string
+	switch t := matter.Tags.(type) {
+	case nil:
+	case string:
+		tags = strings.Join(splitTags(t), ", ")
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, v := range t {
+			if tag := strings.TrimSpace(fmt.Sprint(v)); tag != "" {
+				parts = append(parts, tag)
+			}
+		}
+		tags = strings.Join(parts, ", ")
+	default:
+		return nil, fmt.Errorf("invalid front matter: tags must be a string or a list")
+	}
+	title := strings.TrimSpace(matter.Title)
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	status := strings.TrimSpace(matter.Status)
+
+	var memories []Memory
+	if !bySection {
+		if content := strings.TrimSpace(body); content != "" {
+			memories = append(memories, Memory{Title: title, Tags: tags, Status: status, Content: content})
+		}
+		return memories, nil
+	}
+	for _, sec := range markdownSections(body) {
+		if sec.text == "" {
+			continue
+		}
+		secTitle := sec.heading
+		if secTitle == "" {
+			secTitle = title
+		}
+		memories = append(memories, Memory{Title: secTitle, Tags: tags, Status: status, Content: sec.text})
+	}
+	return memories, nil
+}
+
+// SimpleMemoryImportMarkdown imports a Markdown file, or every .md file in a directory, as
+// simple-memories, one per file or one per section. All files are parsed before anything
+// is written, and the memories are added in a single transaction.
+func (s *SimpleMemoryServer) SimpleMemoryImportMarkdown(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	pathParam, err := req.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	split := strings.ToLower(strings.TrimSpace(req.GetString("split", "file")))
+	if split != "file" && split != "section" {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid split %q: must be file or section", split)), nil
+	}
+	path, err := s.toolPath(pathParam, true)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read %s: %v", path, err)), nil
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.md")); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to list %s: %v", path, err)), nil
+		}
+		sort.Strings(files)
+	}
+
+	var memories []Memory
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read %s, nothing was imported: %v", file, err)), nil
+		}
+		parsed, err := parseMarkdownNote(file, string(data), split == "section")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to parse %s, nothing was imported: %v", file, err)), nil
+		}
+		// Hold every note to the same rules as simple_memory_add before writing any.
+		for i, m := range parsed {
+			m.Content = s.trimField("content", m.Content)
+			if strings.TrimSpace(m.Content) == "" {
+				return mcp.NewToolResultError(fmt.Sprintf("note %q in %s has no content, nothing was imported", m.Title, file)), nil
+			}
+			if err := s.checkContentLength(m.Content); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("note %q in %s is invalid, nothing was imported: %v", m.Title, file, err)), nil
+			}
+			parsed[i] = m
+		}
+		memories = append(memories, parsed...)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start transaction: %v", err)), nil
+	}
+	defer func() { _ = tx.Rollback() }()
+	stmt, err := tx.Prepare("INSERT INTO " + s.table + " (title, tags, status, content) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to import simple-memories: %v", err)), nil
+	}
+	defer stmt.Close()
+	for _, m := range memories {
+		if _, err := stmt.Exec(m.Title, mergeTags(m.Tags, s.contextTags), m.Status, m.Content); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to import simple-memories: %v", err)), nil
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to import simple-memories: %v", err)), nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Imported %d simple-memories from %d Markdown files in %q", len(memories), len(files), path)
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Imported %d simple-memories from %d Markdown files.", len(memories), len(files))), nil
+}
+
+// legacyTimestampLayouts are created_at formats found in older or hand-edited databases,
+// all read as UTC when they carry no zone.
+var legacyTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// timestampAnomaly is a created_at value that is not in createdAtFormat or that sorts before
+// the created_at of a memory with a lower ID.
+type timestampAnomaly struct {
+	ID         int64  `json:"id"`
+	CreatedAt  string `json:"created_at"`
+	Normalized string `json:"normalized,omitempty"`
+	// Problem is "malformed" (fixable), "unparseable", or "out_of_order".
+	Problem string `json:"problem"`
+}
+
+// SimpleMemoryFixTimestamps scans created_at in ID order for values not in the canonical
+// createdAtFormat, which break string-based time sorting, and for values older than those
+// of earlier IDs. With fix, parseable malformed values are rewritten in the canonical format;
+// the other anomalies are only reported.
+func (s *SimpleMemoryServer) SimpleMemoryFixTimestamps(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	fix := req.GetBool("fix", false)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to start transaction: %v", err)), nil
+	}
+	defer func() { _ = tx.Rollback() }()
+	// CAST keeps the driver from converting DATETIME values, so the stored text is seen as is.
+	rows, err := tx.Query("SELECT id, CAST(created_at AS TEXT) FROM " + s.table + " ORDER BY id ASC")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	var (
+		anomalies []timestampAnomaly
+		scanned   int
+		latest    string
+	)
+	for rows.Next() {
+		var (
+			id  int64
+			raw sql.NullString
+		)
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+		}
+		scanned++
+		normalized := ""
+		for _, layout := range legacyTimestampLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(raw.String)); err == nil {
+				normalized = t.UTC().Format(createdAtFormat)
+				break
+			}
+		}
+		switch {
+		case normalized == "":
+			anomalies = append(anomalies, timestampAnomaly{ID: id, CreatedAt: raw.String, Problem: "unparseable"})
+			continue
+		case normalized != raw.String:
+			anomalies = append(anomalies, timestampAnomaly{ID: id, CreatedAt: raw.String, Normalized: normalized, Problem: "malformed"})
+		}
+		if normalized < latest {
+			anomalies = append(anomalies, timestampAnomaly{ID: id, CreatedAt: raw.String, Normalized: normalized, Problem: "out_of_order"})
+		} else {
+			latest = normalized
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+
+	fixed := 0
+	if fix {
+		stmt, err := tx.Prepare("UPDATE " + s.table + " SET created_at = ? WHERE id = ?")
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to fix timestamps: %v", err)), nil
+		}
+		defer stmt.Close()
+		for _, a := range anomalies {
+			if a.Problem != "malformed" {
+				continue
+			}
+			if _, err := stmt.Exec(a.Normalized, a.ID); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("failed to fix timestamps: %v", err)), nil
+			}
+			fixed++
+		}
+		if err := tx.Commit(); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to fix timestamps: %v", err)), nil
+		}
+		if !s.disableLogging {
+			s.logger.Printf("[INFO] Normalized %d malformed created_at values", fixed)
+		}
+	}
+	if anomalies == nil {
+		anomalies = []timestampAnomaly{}
+	}
+	out, err := json.Marshal(struct {
+		Scanned   int                `json:"scanned"`
+		Fixed     int                `json:"fixed"`
+		Anomalies []timestampAnomaly `json:"anomalies"`
+	}{scanned, fixed, anomalies})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// tokenSet returns the set of lowercased words (two or more letters or digits) in text.
+func tokenSet(text string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(w) >= 2 {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|, or 0 if both sets are empty.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// lowerTagSet returns the set of lowercased tags in a comma-separated tag string.
+func lowerTagSet(tags string) map[string]bool {
+	set := map[string]bool{}
+	for _, t := range splitTags(tags) {
+		set[strings.ToLower(t)] = true
+	}
+	return set
+}
+
+// similarity scores how alike two memories are: word overlap of title and content, weighted
+// 0.7, plus tag overlap, weighted 0.3. Scores range from 0 to 1.
+func similarity(a, b Memory) float64 {
+	words := jaccard(tokenSet(a.Title+" "+a.Content), tokenSet(b.Title+" "+b.Content))
+	tags := jaccard(lowerTagSet(a.Tags), lowerTagSet(b.Tags))
+	return 0.7*words + 0.3*tags
+}
+
+// SimpleMemorySimilar ranks all other simple-memories by similarity to the memory with the given ID.
+func (s *SimpleMemoryServer) SimpleMemorySimilar(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireInt("id")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	topK := req.GetInt("top_k", 0)
+	rows, err := s.db.Query("SELECT id, title, tags, status, content, CAST(created_at AS TEXT) FROM " + s.view + " ORDER BY id ASC")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	var (
+		ref   Memory
+		found bool
+	)
+	for _, m := range memories {
+		if m.ID == int64(id) {
+			ref, found = m, true
+			break
+		}
+	}
+	if !found {
+		return mcp.NewToolResultError(fmt.Sprintf("no memory found with id %d", id)), nil
+	}
+	type scoredMemory struct {
+		Memory
+		Score float64 `json:"score"`
+	}
+	ranked := []scoredMemory{}
+	for _, m := range memories {
+		if m.ID != ref.ID {
+			ranked = append(ranked, scoredMemory{Memory: m, Score: math.Round(similarity(ref, m)*1000) / 1000})
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	if topK > 0 && len(ranked) > topK {
+		ranked = ranked[:topK]
+	}
+	if s.structuredResults {
+		return structuredResult(ranked, nil)
+	}
+	out, err := json.Marshal(ranked)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode simple-memories: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// Near-duplicate detection hashes each memory's shingle set with minHashes MinHash functions
+// and only compares memories that agree on every row of at least one of the minHashBands
+// bands. With 2 rows per band, pairs with Jaccard similarity 0.3 are compared with
+// probability 0.95 and pairs at 0.5 or above almost surely, while dissimilar pairs are skipped.
+const (
+	minHashBands = 32
+	minHashRows  = 2
+	minHashes    = minHashBands * minHashRows
+	// defaultDuplicateThreshold and defaultShingleSize apply when simple_memory_near_duplicates
+	// is called without them.
+	defaultDuplicateThreshold = 0.6
+	defaultShingleSize        = 2
+)
+
+// shingles returns the set of k-word shingles of text, lowercased with punctuation removed.
+// Text shorter than k words yields a single shingle of all its words.
+func shingles(text string, k int) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := map[string]bool{}
+	if len(words) > 0 && len(words) < k {
+		set[strings.Join(words, " ")] = true
+	}
+	for i := 0; i+k <= len(words); i++ {
+		set[strings.Join(words[i:i+k], " ")] = true
+	}
+	return set
+}
+
+// minHashSignature returns the minimum of each of minHashes seeded hashes over set.
+func minHashSignature(set map[string]bool) [minHashes]uint64 {
+	var sig [minHashes]uint64
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for shingle := range set {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		x := h.Sum64()
+		for i := range sig {
+			// splitmix64 finalizer over the shingle hash mixed with the function's seed
+			z := x + uint64(i+1)*0x9e3779b97f4a7c15
+			z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+			z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+			z ^= z >> 31
+			sig[i] = min(sig[i], z)
+		}
+	}
+	return sig
+}
+
+// duplicatePair is the similarity of two memories in a near-duplicate cluster.
+type duplicatePair struct {
+	A     int64   `json:"a"`
+	B     int64   `json:"b"`
+	Score float64 `json:"score"`
+}
+
+// duplicateCluster is a group of memories linked by pairs at or above the threshold, with
+// the scores of every pair in the group so the threshold can be tuned.
+type duplicateCluster struct {
+	IDs   []int64         `json:"ids"`
+	Pairs []duplicatePair `json:"pairs"`
+}
+
+// nearDuplicates clusters memories whose content shingle sets have a Jaccard similarity of at
+// least threshold, joining clusters transitively. It returns the clusters, largest first, and
+// how many pairs were compared exactly.
+func nearDuplicates(memories []Memory, k int, threshold float64) ([]duplicateCluster, int) {
+	sets := make([]map[string]bool, len(memories))
+	buckets := map[[minHashRows + 1]uint64][]int{}
+	for i, m := range memories {
+		sets[i] = shingles(m.Content, k)
+		if len(sets[i]) == 0 {
+			continue
+		}
+		sig := minHashSignature(sets[i])
+		for band := 0; band < minHashBands; band++ {
+			var key [minHashRows + 1]uint64
+			key[0] = uint64(band)
+			copy(key[1:], sig[band*minHashRows:(band+1)*minHashRows])
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	parent := make([]int, len(memories))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	scores := map[[2]int]float64{}
+	for _, bucket := range buckets {
+		for x := 0; x < len(bucket); x++ {
+			for y := x + 1; y < len(bucket); y++ {
+				pair := [2]int{bucket[x], bucket[y]}
+				if _, seen := scores[pair]; seen {
+					continue
+				}
+				score := jaccard(sets[pair[0]], sets[pair[1]])
+				scores[pair] = score
+				if score >= threshold {
+					parent[find(pair[0])] = find(pair[1])
+				}
+			}
+		}
+	}
+
+	members := map[int][]int{}
+	for i := range memories {
+		root := find(i)
+		members[root] = append(members[root], i)
+	}
+	clusters := []duplicateCluster{}
+	for _, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		c := duplicateCluster{Pairs: []duplicatePair{}}
+		for x, i := range group {
+			c.IDs = append(c.IDs, memories[i].ID)
+			for _, j := range group[x+1:] {
+				// Pairs the banding never compared are scored now, so every pair is reported.
+				score, ok := scores[[2]int{i, j}]
+				if !ok {
+					score = jaccard(sets[i], sets[j])
+				}
+				c.Pairs = append(c.Pairs, duplicatePair{A: memories[i].ID, B: memories[j].ID, Score: math.Round(score*1000) / 1000})
+			}
+		}
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].IDs) != len(clusters[j].IDs) {
+			return len(clusters[i].IDs) > len(clusters[j].IDs)
+		}
+		return clusters[i].IDs[0] < clusters[j].IDs[0]
+	})
+	return clusters, len(scores)
+}
+
+// SimpleMemoryNearDuplicates groups simple-memories, optionally restricted to a query and
+// filters, whose contents are near-duplicates by shingle Jaccard similarity.
+func (s *SimpleMemoryServer) SimpleMemoryNearDuplicates(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	threshold := req.GetFloat("threshold", defaultDuplicateThreshold)
+	if threshold <= 0 || threshold > 1 {
+		return mcp.NewToolResultError("invalid params: threshold must be greater than 0 and at most 1"), nil
+	}
+	k := req.GetInt("shingle_size", defaultShingleSize)
+	if k < 1 {
+		return mcp.NewToolResultError("invalid params: shingle_size must be at least 1"), nil
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	join, conds, args := s.matchSQL(strings.TrimSpace(req.GetString("query", "")), filter)
+	rows, err := s.db.Query(`
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds)+`
+		ORDER BY m.id ASC
+	`, args...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	clusters, compared := nearDuplicates(memories, k, threshold)
+	out, err := json.Marshal(struct {
+		Threshold   float64            `json:"threshold"`
+		ShingleSize int                `json:"shingle_size"`
+		Scanned     int                `json:"scanned"`
+		Compared    int                `json:"compared"`
+		Clusters    []duplicateCluster `json:"clusters"`
+	}{threshold, k, len(memories), compared, clusters})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// defaultPIIPatterns are the built-in simple_memory_scan_pii detectors by category. Numbers
+// found by credit_card must also pass the Luhn check, which rules out most IDs and timestamps.
+// phone needs a country code, an area code in parentheses, or separators between the groups,
+// so bare 10-digit runs such as Unix timestamps and ticket numbers are not flagged.
+var defaultPIIPatterns = map[string]string{
+	"email":       `[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`,
+	"phone":       `\+\d{1,3}[ .-]?(?:\(\d{3}\)|\d{3})[ .-]?\d{3}[ .-]?\d{4}\b|\(\d{3}\)[ .-]?\d{3}[ .-]?\d{4}\b|\b\d{3}[ .-]\d{3}[ .-]\d{4}\b`,
+	"credit_card": `\b(?:\d[ -]?){12,18}\d\b`,
+}
+
+// compilePIIPatterns compiles defaultPIIPatterns overlaid with overrides, skipping categories
+// whose pattern is empty.
+func compilePIIPatterns(overrides map[string]string) (map[string]*regexp.Regexp, error) {
+	patterns := maps.Clone(defaultPIIPatterns)
+	maps.Copy(patterns, overrides)
+	detectors := map[string]*regexp.Regexp{}
+	for category, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern for %s: %w", category, err)
+		}
+		detectors[category] = re
+	}
+	return detectors, nil
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum used by card numbers.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// maskPII hides all but the first and last two characters of value, so a reviewer can tell
+// matches apart without the scan output leaking them.
+func maskPII(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 6 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:2]) + strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-2:])
+}
+
+// piiMatch is one masked value found by a PII detector.
+type piiMatch struct {
+	Category string `json:"category"`
+	Field    string `json:"field"`
+	Masked   string `json:"masked"`
+}
+
+// piiFinding lists the PII categories found in one memory.
+type piiFinding struct {
+	ID         int64      `json:"id"`
+	Title      string     `json:"title"`
+	Categories []string   `json:"categories"`
+	Matches    []piiMatch `json:"matches"`
+}
+
+// scanPII runs the detectors in categories over the title and content of m.
+func (s *SimpleMemoryServer) scanPII(m Memory, categories []string) []piiMatch {
+	var matches []piiMatch
+	for _, category := range categories {
+		for _, field := range []struct{ name, text string }{{"title", m.Title}, {"content", m.Content}} {
+			for _, value := range s.piiDetectors[category].FindAllString(field.text, -1) {
+				if category == "credit_card" && !luhnValid(value) {
+					continue
+				}
+				matches = append(matches, piiMatch{Category: category, Field: field.name, Masked: maskPII(value)})
+			}
+		}
+	}
+	return matches
+}
+
+// SimpleMemoryScanPII reports which simple-memories, optionally restricted to a query and
+// filters, contain likely PII such as email addresses, phone numbers, or card numbers.
+func (s *SimpleMemoryServer) SimpleMemoryScanPII(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	categories := slices.Sorted(maps.Keys(s.piiDetectors))
+	if requested := req.GetStringSlice("categories", nil); len(requested) > 0 {
+		categories = categories[:0]
+		for _, category := range requested {
+			category = strings.ToLower(strings.TrimSpace(category))
+			if _, ok := s.piiDetectors[category]; !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid params: unknown PII category %q: must be one of %s", category, strings.Join(slices.Sorted(maps.Keys(s.piiDetectors)), ", "))), nil
+			}
+			if !slices.Contains(categories, category) {
+				categories = append(categories, category)
+			}
+		}
+	}
+	filter, err := filterFromRequest(req)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	join, conds, args := s.matchSQL(strings.TrimSpace(req.GetString("query", "")), filter)
+	rows, err := s.db.Query(`
+		SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT)
+		FROM `+s.view+` m
+		`+join+`
+		`+whereClause(conds)+`
+		ORDER BY m.id ASC
+	`, args...)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	memories, err := scanMemories(rows)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	findings := []piiFinding{}
+	for _, m := range memories {
+		matches := s.scanPII(m, categories)
+		if len(matches) == 0 {
+			continue
+		}
+		found := []string{}
+		for _, match := range matches {
+			if !slices.Contains(found, match.Category) {
+				found = append(found, match.Category)
+			}
+		}
+		findings = append(findings, piiFinding{ID: m.ID, Title: m.Title, Categories: found, Matches: matches})
+	}
+	out, err := json.Marshal(struct {
+		Categories []string     `json:"categories"`
+		Scanned    int          `json:"scanned"`
+		Flagged    int          `json:"flagged"`
+		Findings   []piiFinding `json:"findings"`
+	}{categories, len(memories), len(findings), findings})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// trendBuckets maps the supported trend bucket sizes to SQL expressions labelling created_at.
+// Weeks are ISO 8601 weeks, labelled by the year and week of their Thursday, so a week that
+// spans New Year is a single bucket (2025-12-29 through 2026-01-04 are all "2026-W01").
+var trendBuckets = map[string]string{
+	"day":   "strftime('%Y-%m-%d', created_at)",
+	"week":  "strftime('%Y', created_at, '-3 days', 'weekday 4') || '-W' || printf('%02d', (strftime('%j', created_at, '-3 days', 'weekday 4') - 1) / 7 + 1)",
+	"month": "strftime('%Y-%m', created_at)",
+}
+
+// SimpleMemoryTrends returns how many simple-memories were created per tag or status in each time bucket.
+func (s *SimpleMemoryServer) SimpleMemoryTrends(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	groupBy := strings.ToLower(strings.TrimSpace(req.GetString("group_by", "tag")))
+	bucket := strings.ToLower(strings.TrimSpace(req.GetString("bucket", "day")))
+	bucketExpr, ok := trendBuckets[bucket]
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid bucket %q: must be day, week, or month", bucket)), nil
+	}
+	var sqlQuery string
+	switch groupBy {
+	case "tag":
+		// Split the comma-separated tags into one row per tag before grouping.
+		sqlQuery = `
+			WITH RECURSIVE split(id, created_at, tag, rest) AS (
+				SELECT id, created_at, '', COALESCE(tags, '') || ',' FROM ` + s.view + `
+				UNION ALL
+				SELECT id, created_at,
+					TRIM(substr(rest, 1, instr(rest, ',') - 1)),
+					substr(rest, instr(rest, ',') + 1)
+				FROM split WHERE rest <> ''
+			)
+			SELECT tag, ` + bucketExpr + ` AS bucket, COUNT(DISTINCT id)
+			FROM split
+			WHERE tag <> ''
+			GROUP BY tag, bucket
+			ORDER BY tag ASC, bucket ASC
+		`
+	case "status":
+		sqlQuery = `
+			SELECT COALESCE(NULLIF(TRIM(status), ''), '(none)') AS key, ` + bucketExpr + ` AS bucket, COUNT(*)
+			FROM ` + s.view + `
+			GROUP BY key, bucket
+			ORDER BY key ASC, bucket ASC
+		`
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("invalid group_by %q: must be tag or status", groupBy)), nil
+	}
+	rows, err := s.db.Query(sqlQuery)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to compute trends: %v", err)), nil
+	}
+	defer rows.Close()
+	type trendPoint struct {
+		Key    string `json:"key"`
+		Bucket string `json:"bucket"`
+		Count  int64  `json:"count"`
+	}
+	points := []trendPoint{}
+	for rows.Next() {
+		var (
+			p      trendPoint
+			bucket sql.NullString
+		)
+		if err := rows.Scan(&p.Key, &bucket, &p.Count); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to compute trends: %v", err)), nil
+		}
+		p.Bucket = bucket.String
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to compute trends: %v", err)), nil
+	}
+	if s.structuredResults {
+		return structuredResult(points, nil)
+	}
+	out, err := json.Marshal(points)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// reminder is a memory whose reminder time has passed.
+type reminder struct {
+	Memory
+	RemindAt string `json:"remind_at"`
+}
+
+// dueReminders returns unacknowledged reminders due at or before asOf and after after
+// (both createdAtFormat timestamps), oldest first.
+func (s *SimpleMemoryServer) dueReminders(after, asOf string) ([]reminder, error) {
+	rows, err := s.db.Query(`
+		SELECT id, title, tags, status, content, CAST(created_at AS TEXT), remind_at
+		FROM `+s.view+`
+		WHERE remind_at IS NOT NULL AND reminder_acked_at IS NULL AND remind_at > ? AND remind_at <= ?
+		ORDER BY remind_at ASC, id ASC
+	`, after, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	due := []reminder{}
+	for rows.Next() {
+		var (
+			r                                       reminder
+			title, tags, status, content, createdAt sql.NullString
+		)
+		if err := rows.Scan(&r.ID, &title, &tags, &status, &content, &createdAt, &r.RemindAt); err != nil {
+			return nil, err
+		}
+		r.Title, r.Tags, r.Status = title.String, tags.String, status.String
+		r.Content, r.CreatedAt = content.String, createdAt.String
+		due = append(due, r)
+	}
+	return due, rows.Err()
+}
+
+// SimpleMemoryDueReminders returns simple-memories whose reminder is due and not yet acknowledged.
+func (s *SimpleMemoryServer) SimpleMemoryDueReminders(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	asOf := time.Now().UTC().Format(createdAtFormat)
+	if v := strings.TrimSpace(req.GetString("as_of", "")); v != "" {
+		ts, err := parseTimestamp(v)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid params: invalid as_of %q: %v", v, err)), nil
+		}
+		asOf = ts
+	}
+	due, err := s.dueReminders("", asOf)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read reminders: %v", err)), nil
+	}
+	if s.structuredResults {
+		return structuredResult(due, nil)
+	}
+	out, err := json.Marshal(due)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode reminders: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// SimpleMemoryAckReminder acknowledges the reminder of the given simple-memory so it is no longer reported as due.
+func (s *SimpleMemoryServer) SimpleMemoryAckReminder(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireInt("id")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid params: %v", err)), nil
+	}
+	res, err := s.db.Exec(
+		"UPDATE "+s.table+" SET reminder_acked_at = ? WHERE id = ? AND remind_at IS NOT NULL",
+		time.Now().UTC().Format(createdAtFormat), id,
+	)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to acknowledge reminder: %v", err)), nil
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("no reminder found for memory with id %d", id)), nil
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Acknowledged reminder of simple-memory %d", id)
+	}
+	return mcp.NewToolResultText(fmt.Sprintf("Reminder for memory %d acknowledged.", id)), nil
+}
+
+// WatchReminders checks for newly due reminders every interval until ctx is done, calling
+// notify once for each reminder as it becomes due. Reminders already overdue when watching
+// starts are reported on the first check.
+func (s *SimpleMemoryServer) WatchReminders(ctx context.Context, interval time.Duration, notify func(method string, params map[string]any)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastCheck := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now().UTC().Format(createdAtFormat)
+		due, err := s.dueReminders(lastCheck, now)
+		if err != nil {
+			if !s.disableLogging {
+				s.logger.Printf("[ERROR] Failed to check reminders: %v", err)
+			}
+			continue
+		}
+		lastCheck = now
+		for _, r := range due {
+			notify("notifications/simple_memory/reminder_due", map[string]any{
+				"id":        r.ID,
+				"title":     r.Title,
+				"content":   r.Content,
+				"remind_at": r.RemindAt,
+			})
+		}
+	}
+}
+
+// healthTable is written by every health probe. It is shared by all namespaces and its name
+// cannot clash with a memory table, which always starts with simple_memories.
+const healthTable = "simple_memory_health"
+
+// HealthStatus is the outcome of the most recent storage health probe.
+type HealthStatus struct {
+	Healthy   bool   `json:"healthy"`
+	CheckedAt string `json:"checked_at"`
+	// Since is when the database last changed between healthy and unhealthy.
+	Since string `json:"since"`
+	Error string `json:"error,omitempty"`
+}
+
+// probeHealth pings the database and performs a small write, so that problems such as a full
+// disk or a database file that was moved or made read-only are noticed. In compatibility mode
+// the write is rolled back, so the newer schema never gains the health table.
+func (s *SimpleMemoryServer) probeHealth(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("write test: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+healthTable+" (name TEXT PRIMARY KEY, checked_at TEXT NOT NULL)"); err != nil {
+		return fmt.Errorf("write test: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO `+healthTable+` (name, checked_at) VALUES (?, strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		ON CONFLICT(name) DO UPDATE SET checked_at = excluded.checked_at
+	`, s.table); err != nil {
+		return fmt.Errorf("write test: %w", err)
+	}
+	if s.compat {
+		return nil
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("write test: %w", err)
+	}
+	return nil
+}
+
+// checkHealth runs one health probe and records its outcome, logging when the database becomes
+// unhealthy and when it recovers.
+func (s *SimpleMemoryServer) checkHealth(ctx context.Context) HealthStatus {
+	err := s.probeHealth(ctx)
+	now := time.Now().UTC().Format(createdAtFormat)
+	status := HealthStatus{Healthy: err == nil, CheckedAt: now, Since: now}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	s.healthMu.Lock()
+	prev := s.health
+	if prev != nil && prev.Healthy == status.Healthy {
+		status.Since = prev.Since
+	}
+	s.health = &status
+	s.healthMu.Unlock()
+	if !s.disableLogging {
+		switch {
+		case err != nil && (prev == nil || prev.Healthy || prev.Error != status.Error):
+			s.logger.Printf("[ERROR] Storage health check failed: %v", err)
+		case err == nil && prev != nil && !prev.Healthy:
+			s.logger.Printf("[INFO] Storage health check recovered")
+		}
+	}
+	return status
+}
+
+// healthStatus returns the latest health probe outcome, or nil if WatchHealth is not running.
+func (s *SimpleMemoryServer) healthStatus() *HealthStatus {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	if s.health == nil {
+		return nil
+	}
+	status := *s.health
+	return &status
+}
+
+// WatchHealth probes the database immediately and then every interval until ctx is done. The
+// latest outcome is reported by simple_memory_count.
+func (s *SimpleMemoryServer) WatchHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.checkHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cp1252Bytes maps the runes Windows-1252 assigns to bytes 0x80-0x9F back to those bytes,
+// so UTF-8 that was decoded as cp1252 can be recognised.
+var cp1252Bytes = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// mojibakeProblems returns a description of each encoding problem found in text.
+func mojibakeProblems(text string) []string {
+	var problems []string
+	if !utf8.ValidString(text) {
+		problems = append(problems, "invalid UTF-8")
+	}
+	// ContainsRune would also match invalid bytes, which are reported above.
+	if strings.Contains(text, string(utf8.RuneError)) {
+		problems = append(problems, "replacement character (U+FFFD)")
+	}
+	if looksDoubleEncoded(text) {
+		problems = append(problems, "possible double-encoded UTF-8")
+	}
+	return problems
+}
+
+// looksDoubleEncoded reports whether text contains a UTF-8 lead byte rendered as Latin-1
+// followed by continuation bytes rendered as Latin-1 or cp1252 (e.g. "Ã©" or "â€™").
+// Two-byte sequences must continue in Latin-1, since a letter such as "ß" or "Ä" followed
+// by cp1252 punctuation such as "…" or "“" is common in valid text.
+func looksDoubleEncoded(text string) bool {
+	runes := []rune(text)
+	for i, r := range runes {
+		var continuations int
+		switch {
+		case r >= 0xC2 && r <= 0xDF:
+			continuations = 1
+		case r >= 0xE0 && r <= 0xEF:
+			continuations = 2
+		case r >= 0xF0 && r <= 0xF4:
+			continuations = 3
+		default:
+			continue
+		}
+		if i+continuations >= len(runes) {
+			continue
+		}
+		seq := []byte{byte(r)}
+		for _, c := range runes[i+1 : i+1+continuations] {
+			b, ok := cp1252Bytes[c]
+			if !ok || continuations == 1 {
+				if c < 0x80 || c > 0xBF {
+					break
+				}
+				b = byte(c)
+			}
+			seq = append(seq, b)
+		}
+		if len(seq) == continuations+1 && utf8.Valid(seq) {
+			return true
+		}
+	}
+	return false
+}
+
+// SimpleMemoryFindMojibake flags simple-memories whose title, tags, or content show signs of encoding corruption.
+func (s *SimpleMemoryServer) SimpleMemoryFindMojibake(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	rows, err := s.db.Query("SELECT id, title, tags, content FROM " + s.view + " ORDER BY id ASC")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	defer rows.Close()
+	type finding struct {
+		ID       int64    `json:"id"`
+		Title    string   `json:"title"`
+		Problems []string `json:"problems"`
+	}
+	var findings []finding
+	for rows.Next() {
+		var (
+			id          int64
+			title, tags sql.NullString
+			content     string
+		)
+		if err := rows.Scan(&id, &title, &tags, &content); err != nil {
+			continue
+		}
+		var problems []string
+		for _, field := range []struct{ name, value string }{
+			{"title", title.String},
+			{"tags", tags.String},
+			{"content", content},
+		} {
+			for _, p := range mojibakeProblems(field.value) {
+				problems = append(problems, field.name+": "+p)
+			}
+		}
+		if len(problems) > 0 {
+			findings = append(findings, finding{ID: id, Title: title.String, Problems: problems})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read simple-memories: %v", err)), nil
+	}
+	if s.structuredResults {
+		return structuredResult(findings, nil)
+	}
+	if len(findings) == 0 {
+		return mcp.NewToolResultText("No encoding problems found."), nil
+	}
+	out, err := json.Marshal(findings)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
+
+// defaultVacuumThreshold is the fraction of free pages at which simple_memory_storage_stats
+// recommends VACUUM.
+const defaultVacuumThreshold = 0.2
+
+// storageStats describes how much of the database file is unused.
+type storageStats struct {
+	PageSize  int64 `json:"page_size"`
+	PageCount int64 `json:"page_count"`
+	FreePages int64 `json:"free_pages"`
+	FileBytes int64 `json:"file_bytes"`
+	FreeBytes int64 `json:"free_bytes"`
+	// Fragmentation is the fraction of pages that are free.
+	Fragmentation float64 `json:"fragmentation"`
+}
+
+// readStorageStats reads the page statistics of the main database.
+func (s *SimpleMemoryServer) readStorageStats() (storageStats, error) {
+	var st storageStats
+	for _, p := range []struct {
+		pragma string
+		dst    *int64
+	}{{"page_size", &st.PageSize}, {"page_count", &st.PageCount}, {"freelist_count", &st.FreePages}} {
+		if err := s.db.QueryRow("PRAGMA " + p.pragma).Scan(p.dst); err != nil {
+			return st, fmt.Errorf("%s: %w", p.pragma, err)
+		}
+	}
+	st.FileBytes = st.PageSize * st.PageCount
+	st.FreeBytes = st.PageSize * st.FreePages
+	if st.PageCount > 0 {
+		st.Fragmentation = math.Round(float64(st.FreePages)/float64(st.PageCount)*1000) / 1000
+	}
+	return st, nil
+}
+
+// SimpleMemoryStorageStats reports the free pages left behind by deletions and recommends
+// VACUUM once they exceed a threshold fraction of the file. With vacuum, it also runs VACUUM
+// when recommended and reports the stats again.
+func (s *SimpleMemoryServer) SimpleMemoryStorageStats(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	threshold := req.GetFloat("threshold", defaultVacuumThreshold)
+	if threshold <= 0 || threshold > 1 {
+		return mcp.NewToolResultError("invalid params: threshold must be greater than 0 and at most 1"), nil
+	}
+	before, err := s.readStorageStats()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to read storage stats: %v", err)), nil
+	}
+	recommended := float64(before.FreePages) >= threshold*float64(before.PageCount) && before.FreePages > 0
+	var after *storageStats
+	if recommended && req.GetBool("vacuum", false) {
+		if _, err := s.db.Exec("VACUUM"); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to vacuum: %v", err)), nil
+		}
+		st, err := s.readStorageStats()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to read storage stats: %v", err)), nil
+		}
+		after = &st
+		if !s.disableLogging {
+			s.logger.Printf("[INFO] Vacuumed database: %d of %d pages were free, now %d pages", before.FreePages, before.PageCount, st.PageCount)
+		}
+	}
+	out, err := json.Marshal(struct {
+		storageStats
+		Threshold         float64       `json:"threshold"`
+		VacuumRecommended bool          `json:"vacuum_recommended"`
+		AfterVacuum       *storageStats `json:"after_vacuum,omitempty"`
+	}{before, threshold, recommended, after})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to encode results: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(out)), nil
+}
@@ -0,0 +1,3319 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// newTestServer returns a server backed by a fresh in-memory database.
+func newTestServer(t *testing.T, cfg Config) *SimpleMemoryServer {
+	t.Helper()
+	db, err := sql.Open(DriverName, ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	// Every connection to :memory: gets its own empty database, so keep to one.
+	db.SetMaxOpenConns(1)
+	s, err := New(db, cfg)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// callTool invokes handler with args and returns the text of the result and whether it is
+// a tool error.
+func callTool(t *testing.T, handler server.ToolHandlerFunc, args map[string]any) (string, bool) {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	res, err := handler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("handler returned error: %v", err)
+	}
+	var text strings.Builder
+	for _, c := range res.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			text.WriteString(tc.Text)
+		}
+	}
+	return text.String(), res.IsError
+}
+
+// mustAdd adds memories and fails the test if any add fails.
+func mustAdd(t *testing.T, s *SimpleMemoryServer, memories ...map[string]any) {
+	t.Helper()
+	for _, m := range memories {
+		if out, isErr := callTool(t, s.SimpleMemoryAdd, m); isErr {
+			t.Fatalf("add %v: %s", m, out)
+		}
+	}
+}
+
+// listIDs returns the IDs of every memory, in list order.
+func listIDs(t *testing.T, s *SimpleMemoryServer) []int64 {
+	t.Helper()
+	out, isErr := callTool(t, s.SimpleMemoryList, map[string]any{"limit": 0})
+	if isErr {
+		t.Fatalf("list: %s", out)
+	}
+	var page memoryPage
+	if err := json.Unmarshal([]byte(out), &page); err != nil {
+		t.Fatalf("decode list %q: %v", out, err)
+	}
+	return memoryIDs(page.Memories)
+}
+
+func memoryIDs(memories []Memory) []int64 {
+	ids := []int64{}
+	for _, m := range memories {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mustJSON encodes v as JSON.
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestSimpleMemoryAdd(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxContentBytes = 24
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantErr string
+		want    Memory
+	}{
+		{
+			name: "content only",
+			args: map[string]any{"memory": "User prefers Go"},
+			want: Memory{Content: "User prefers Go"},
+		},
+		{
+			name: "fields are trimmed",
+			args: map[string]any{"memory": "  padded  ", "title": " T ", "tags": " a, b ", "status": " open "},
+			want: Memory{Title: "T", Tags: "a, b", Status: "open", Content: "padded"},
+		},
+		{
+			name: "unicode content",
+			args: map[string]any{"memory": "Ünïcödé ☕ 日本"},
+			want: Memory{Content: "Ünïcödé ☕ 日本"},
+		},
+		{name: "missing memory", args: map[string]any{}, wantErr: "invalid params"},
+		{name: "empty memory", args: map[string]any{"memory": ""}, wantErr: "memory cannot be empty"},
+		{name: "whitespace memory", args: map[string]any{"memory": " \n\t "}, wantErr: "memory cannot be empty"},
+		{
+			name:    "oversized memory counts bytes",
+			args:    map[string]any{"memory": strings.Repeat("é", 13)},
+			wantErr: "memory exceeds max size of 24 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, cfg)
+			out, isErr := callTool(t, s.SimpleMemoryAdd, tt.args)
+			if tt.wantErr != "" {
+				if !isErr || !strings.Contains(out, tt.wantErr) {
+					t.Fatalf("got %q (error=%v), want error containing %q", out, isErr, tt.wantErr)
+				}
+				if ids := listIDs(t, s); len(ids) != 0 {
+					t.Fatalf("failed add stored memories %v", ids)
+				}
+				return
+			}
+			if isErr {
+				t.Fatalf("unexpected error: %s", out)
+			}
+			out, _ = callTool(t, s.SimpleMemoryGet, map[string]any{"id": 1})
+			var got Memory
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("decode get %q: %v", out, err)
+			}
+			if got.Title != tt.want.Title || got.Tags != tt.want.Tags || got.Status != tt.want.Status || got.Content != tt.want.Content {
+				t.Fatalf("stored %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTemplate(t *testing.T) {
+	dir := t.TempDir()
+	for name, body := range map[string]string{
+		"bug.tmpl":    "Bug: {{.summary}}\nSteps: {{.steps}}\nSeverity: {{.severity}}",
+		"broken.tmpl": "{{.summary",
+		"notes.txt":   "not a template",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name        string
+		templateDir string
+		args        map[string]any
+		want        string
+		wantErr     string
+	}{
+		{
+			name: "rendered",
+			args: map[string]any{"template": "bug", "tags": "bug", "variables": map[string]any{"summary": "crash on save", "steps": "open, save", "severity": 2}},
+			want: "Bug: crash on save\nSteps: open, save\nSeverity: 2",
+		},
+		{
+			name:    "missing field",
+			args:    map[string]any{"template": "bug", "variables": map[string]any{"summary": "crash on save", "steps": "open, save"}},
+			wantErr: `failed to render template "bug": template: bug:3:12: executing "bug" at <.severity>: map has no entry for key "severity"`,
+		},
+		{
+			name:    "no variables",
+			args:    map[string]any{"template": "bug"},
+			wantErr: `map has no entry for key "summary"`,
+		},
+		{name: "missing template", args: map[string]any{"template": "feature"}, wantErr: `template "feature" not found`},
+		{name: "other extension ignored", args: map[string]any{"template": "notes"}, wantErr: `template "notes" not found`},
+		{name: "path in name", args: map[string]any{"template": "../bug"}, wantErr: `invalid template name "../bug"`},
+		{name: "parse error", args: map[string]any{"template": "broken"}, wantErr: `failed to parse template "broken"`},
+		{
+			name:    "variables not an object",
+			args:    map[string]any{"template": "bug", "variables": "summary=crash"},
+			wantErr: "invalid params: variables must be an object",
+		},
+		{
+			name:        "templates not configured",
+			templateDir: "-",
+			args:        map[string]any{"template": "bug", "variables": map[string]any{}},
+			wantErr:     "templates are not configured (set SIMPLE_MEMORY_TEMPLATE_DIR)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.TemplateDir = dir
+			if tt.templateDir == "-" {
+				cfg.TemplateDir = ""
+			}
+			s := newTestServer(t, cfg)
+			out, isErr := callTool(t, s.SimpleMemoryAdd, tt.args)
+			if tt.wantErr != "" {
+				if !isErr || !strings.Contains(out, tt.wantErr) {
+					t.Fatalf("got %q (error=%v), want an error containing %q", out, isErr, tt.wantErr)
+				}
+				if ids := listIDs(t, s); len(ids) != 0 {
+					t.Fatalf("failed add stored %v", ids)
+				}
+				return
+			}
+			if isErr {
+				t.Fatalf("add: %s", out)
+			}
+			memories, err := s.allMemories()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(memories) != 1 || memories[0].Content != tt.want {
+				t.Fatalf("stored %+v, want content %q", memories, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractTitle(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]any
+		wantTitle   string
+		wantContent string
+	}{
+		{
+			name:        "markdown heading",
+			args:        map[string]any{"memory": "## Deploy steps\n\nRun make release."},
+			wantTitle:   "Deploy steps",
+			wantContent: "Run make release.",
+		},
+		{
+			name:        "short line without period",
+			args:        map[string]any{"memory": "Chi router\nUser prefers Chi over Gin."},
+			wantTitle:   "Chi router",
+			wantContent: "User prefers Chi over Gin.",
+		},
+		{
+			name:        "sentence first line",
+			args:        map[string]any{"memory": "User prefers Chi.\nAlso likes sqlc."},
+			wantContent: "User prefers Chi.\nAlso likes sqlc.",
+		},
+		{
+			name:        "list item first line",
+			args:        map[string]any{"memory": "- buy milk\n- buy eggs"},
+			wantContent: "- buy milk\n- buy eggs",
+		},
+		{
+			name:        "long first line",
+			args:        map[string]any{"memory": strings.Repeat("word ", 20) + "\nmore"},
+			wantContent: strings.Repeat("word ", 20) + "\nmore",
+		},
+		{
+			name:        "single line",
+			args:        map[string]any{"memory": "# Only a heading"},
+			wantContent: "# Only a heading",
+		},
+		{
+			name:        "hashtag is not a heading",
+			args:        map[string]any{"memory": "#golang tips\nUse go vet."},
+			wantContent: "#golang tips\nUse go vet.",
+		},
+		{
+			name:        "explicit title wins",
+			args:        map[string]any{"memory": "# Heading\nbody", "title": "Given"},
+			wantTitle:   "Given",
+			wantContent: "# Heading\nbody",
+		},
+	}
+	cfg := DefaultConfig()
+	cfg.ExtractTitle = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, cfg)
+			mustAdd(t, s, tt.args)
+			out, _ := callTool(t, s.SimpleMemoryGet, map[string]any{"id": 1})
+			var got Memory
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("decode get %q: %v", out, err)
+			}
+			if got.Title != tt.wantTitle || got.Content != tt.wantContent {
+				t.Fatalf("stored title %q content %q, want %q and %q", got.Title, got.Content, tt.wantTitle, tt.wantContent)
+			}
+		})
+	}
+
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s, map[string]any{"memory": "# Heading\nbody"})
+	if out, _ := callTool(t, s.SimpleMemoryGet, map[string]any{"id": 1}); !strings.Contains(out, `"title":""`) {
+		t.Fatalf("title extracted without opting in: %s", out)
+	}
+}
+
+func TestContextTags(t *testing.T) {
+	tests := []struct {
+		name        string
+		contextTags string
+		tags        string
+		want        string
+	}{
+		{name: "no user tags", contextTags: "proj-x, work", want: "proj-x,work"},
+		{name: "blank user tags", contextTags: "proj-x, work", tags: " , ", want: "proj-x,work"},
+		{name: "appended after user tags", contextTags: "proj-x, work", tags: "go", want: "go,proj-x,work"},
+		{name: "duplicates ignore case and keep the user's spelling", contextTags: "proj-x, work", tags: "Work, go, GO", want: "Work,go,proj-x"},
+		{name: "duplicate context tags", contextTags: "work, WORK, work", tags: "", want: "work"},
+		{name: "no context tags leaves tags as given", tags: "go, http, go", want: "go, http, go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.ContextTags = splitTags(tt.contextTags)
+			s := newTestServer(t, cfg)
+			mustAdd(t, s, map[string]any{"memory": "note", "tags": tt.tags})
+			out, isErr := callTool(t, s.SimpleMemoryGet, map[string]any{"id": 1})
+			var m Memory
+			if isErr || json.Unmarshal([]byte(out), &m) != nil {
+				t.Fatalf("get: %s", out)
+			}
+			if m.Tags != tt.want {
+				t.Fatalf("tags %q, want %q", m.Tags, tt.want)
+			}
+		})
+	}
+
+	t.Run("digest and updates", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.ContextTags = []string{"proj-x"}
+		s := newTestServer(t, cfg)
+		mustAdd(t, s, map[string]any{"memory": "one", "tags": "go"}, map[string]any{"memory": "two", "tags": "go"})
+		out, isErr := callTool(t, s.SimpleMemoryDigest, map[string]any{"tags": "go", "dry_run": true})
+		if isErr || !strings.Contains(out, `"tags":"go,digest,proj-x"`) {
+			t.Fatalf("digest: got %s (error=%v), want tags go,digest,proj-x", out, isErr)
+		}
+		// Context tags mark new memories only; an update keeps the tags it is given.
+		if out, isErr := callTool(t, s.SimpleMemoryUpdate, map[string]any{"id": 1, "tags": "rust"}); isErr {
+			t.Fatalf("update: %s", out)
+		}
+		if out, _ := callTool(t, s.SimpleMemoryGet, map[string]any{"id": 1}); !strings.Contains(out, `"tags":"rust"`) {
+			t.Fatalf("updated memory %s, want tags rust", out)
+		}
+	})
+}
+
+func TestMinContentChars(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MinContentChars = 4
+	tests := []struct {
+		name    string
+		memory  string
+		wantErr bool
+	}{
+		{name: "at minimum", memory: "Go 1"},
+		{name: "below minimum", memory: "Go1", wantErr: true},
+		{name: "multi-byte at minimum", memory: "日本語!"},
+		{name: "multi-byte below minimum", memory: "日本語", wantErr: true},
+		{name: "surrounding space not counted", memory: "  ab  ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, cfg)
+			out, isErr := callTool(t, s.SimpleMemoryAdd, map[string]any{"memory": tt.memory})
+			if isErr != tt.wantErr || (isErr && !strings.Contains(out, "memory is too short")) {
+				t.Fatalf("add %q: got %q (error=%v), want error=%v", tt.memory, out, isErr, tt.wantErr)
+			}
+			mustAdd(t, s, map[string]any{"memory": "long enough"})
+			id := len(listIDs(t, s))
+			out, isErr = callTool(t, s.SimpleMemoryUpdate, map[string]any{"id": id, "memory": tt.memory})
+			if isErr != tt.wantErr || (isErr && !strings.Contains(out, "memory is too short")) {
+				t.Fatalf("update %q: got %q (error=%v), want error=%v", tt.memory, out, isErr, tt.wantErr)
+			}
+			out, isErr = callTool(t, s.SimpleMemoryImport, map[string]any{"memories": []any{map[string]any{"content": tt.memory}}})
+			if isErr != tt.wantErr || (isErr && !strings.Contains(out, "memory is too short")) {
+				t.Fatalf("import %q: got %q (error=%v), want error=%v", tt.memory, out, isErr, tt.wantErr)
+			}
+			mustAdd(t, s, map[string]any{"memory": "xyzzy marker"})
+			out, isErr = callTool(t, s.SimpleMemoryTransform, map[string]any{"query": "xyzzy", "find": "xyzzy marker", "replace": tt.memory})
+			if isErr != tt.wantErr || (isErr && !strings.Contains(out, "memory is too short")) {
+				t.Fatalf("transform to %q: got %q (error=%v), want error=%v", tt.memory, out, isErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPreserveWhitespace(t *testing.T) {
+	preserved, err := preservedFields("title, TAGS,status")
+	if err != nil || !slices.Equal(preserved, []string{"content"}) {
+		t.Fatalf("preservedFields = %v, %v; want [content]", preserved, err)
+	}
+	if _, err := preservedFields("title,body"); err == nil {
+		t.Fatal("preservedFields accepted unknown field body")
+	}
+
+	cfg := DefaultConfig()
+	cfg.PreserveWhitespace = preserved
+	s := newTestServer(t, cfg)
+	code := "    func main() {\n        run()\n    }\n"
+	mustAdd(t, s, map[string]any{"memory": code, "title": "  Snippet  "})
+	get := func() Memory {
+		t.Helper()
+		out, _ := callTool(t, s.SimpleMemoryGet, map[string]any{"id": 1})
+		var m Memory
+		if err := json.Unmarshal([]byte(out), &m); err != nil {
+			t.Fatalf("decode get %q: %v", out, err)
+		}
+		return m
+	}
+	if m := get(); m.Content != code || m.Title != "Snippet" {
+		t.Fatalf("stored content %q title %q, want content untouched and title trimmed", m.Content, m.Title)
+	}
+	if out, isErr := callTool(t, s.SimpleMemoryUpdate, map[string]any{"id": 1, "memory": "\t" + code}); isErr {
+		t.Fatalf("update: %s", out)
+	}
+	if m := get(); m.Content != "\t"+code {
+		t.Fatalf("updated content %q, want %q", m.Content, "\t"+code)
+	}
+	if out, isErr := callTool(t, s.SimpleMemoryAdd, map[string]any{"memory": " \n\t "}); !isErr {
+		t.Fatalf("whitespace-only memory: got %q, want error", out)
+	}
+}
+
+func TestSimpleMemoryGetFormat(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "SELECT 1;", "title": "Ping"},
+		map[string]any{"memory": "Use ```go fences``` in docs"},
+	)
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", args: map[string]any{"id": 1, "format": "plain"}, want: "SELECT 1;"},
+		{name: "fenced with language", args: map[string]any{"id": 1, "format": "markdown_code", "language": "sql"}, want: "```sql\nSELECT 1;\n```"},
+		{name: "fenced without language", args: map[string]any{"id": 1, "format": "markdown_code"}, want: "```\nSELECT 1;\n```"},
+		{name: "fence outgrows backticks in content", args: map[string]any{"id": 2, "format": "markdown_code"}, want: "````\nUse ```go fences``` in docs\n````"},
+		{name: "unknown format", args: map[string]any{"id": 1, "format": "html"}, wantErr: true},
+		{name: "language with space", args: map[string]any{"id": 1, "format": "markdown_code", "language": "go run"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemoryGet, tt.args)
+			if isErr != tt.wantErr {
+				t.Fatalf("get: error=%v, want %v: %s", isErr, tt.wantErr, out)
+			}
+			if tt.wantErr {
+				return
+			}
+			if out != tt.want {
+				t.Fatalf("got %q, want %q", out, tt.want)
+			}
+		})
+	}
+	if out, _ := callTool(t, s.SimpleMemoryGet, map[string]any{"id": 1}); !strings.Contains(out, `"title":"Ping"`) {
+		t.Fatalf("default format: got %q, want the JSON object", out)
+	}
+}
+
+func TestSimpleMemoryList(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "one", "tags": "work", "status": "open"},
+		map[string]any{"memory": "two", "tags": "homework"},
+		map[string]any{"memory": "three", "tags": "work, urgent", "status": "done"},
+		map[string]any{"memory": "four", "status": "open"},
+	)
+	tests := []struct {
+		name      string
+		args      map[string]any
+		wantIDs   []int64
+		wantTotal int64
+	}{
+		{name: "all", args: nil, wantIDs: []int64{1, 2, 3, 4}, wantTotal: 4},
+		{name: "limit", args: map[string]any{"limit": 2}, wantIDs: []int64{1, 2}, wantTotal: 4},
+		{name: "offset", args: map[string]any{"offset": 2}, wantIDs: []int64{3, 4}, wantTotal: 4},
+		{name: "offset past end", args: map[string]any{"offset": 5}, wantIDs: []int64{}, wantTotal: 4},
+		{name: "whole tag", args: map[string]any{"tags": "work"}, wantIDs: []int64{1, 3}, wantTotal: 2},
+		{name: "status", args: map[string]any{"status": "done"}, wantIDs: []int64{3}, wantTotal: 1},
+		{name: "single status", args: map[string]any{"statuses": []any{"done"}}, wantIDs: []int64{3}, wantTotal: 1},
+		{name: "multiple statuses", args: map[string]any{"statuses": []any{"open", "done"}}, wantIDs: []int64{1, 3, 4}, wantTotal: 3},
+		{name: "empty statuses", args: map[string]any{"statuses": []any{}}, wantIDs: []int64{1, 2, 3, 4}, wantTotal: 4},
+		{name: "statuses and tags", args: map[string]any{"statuses": []any{"open", "in-progress"}, "tags": "work"}, wantIDs: []int64{1}, wantTotal: 1},
+		{name: "untagged", args: map[string]any{"untagged": true}, wantIDs: []int64{4}, wantTotal: 1},
+		{name: "untagged with status", args: map[string]any{"untagged": true, "status": "open"}, wantIDs: []int64{4}, wantTotal: 1},
+		{name: "untagged with other status", args: map[string]any{"untagged": true, "status": "done"}, wantIDs: []int64{}, wantTotal: 0},
+		{name: "exclude tags", args: map[string]any{"exclude_tags": "urgent, homework"}, wantIDs: []int64{1, 4}, wantTotal: 2},
+		{name: "exclude whole tag only", args: map[string]any{"exclude_tags": "work"}, wantIDs: []int64{2, 4}, wantTotal: 2},
+		{name: "exclude tags with status", args: map[string]any{"exclude_tags": "urgent", "statuses": []any{"open", "done"}}, wantIDs: []int64{1, 4}, wantTotal: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemoryList, tt.args)
+			if isErr {
+				t.Fatalf("list: %s", out)
+			}
+			var page memoryPage
+			if err := json.Unmarshal([]byte(out), &page); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if ids := memoryIDs(page.Memories); !equalIDs(ids, tt.wantIDs) || page.Total != tt.wantTotal {
+				t.Fatalf("got ids %v total %d, want %v total %d", ids, page.Total, tt.wantIDs, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestSinceUntil(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantIDs []int64
+		wantErr string
+	}{
+		{name: "since a date", args: map[string]any{"since": "2024-06-03"}, wantIDs: []int64{3, 4, 5}},
+		{name: "since is inclusive", args: map[string]any{"since": "2024-06-03T00:00:00Z"}, wantIDs: []int64{3, 4, 5}},
+		{name: "until is exclusive", args: map[string]any{"until": "2024-06-03T00:00:00Z"}, wantIDs: []int64{1, 2}},
+		{name: "one week", args: map[string]any{"since": "2024-06-03", "until": "2024-06-10"}, wantIDs: []int64{3, 4}},
+		{name: "offset converted to UTC", args: map[string]any{"since": "2024-06-03T01:30:00+02:00", "until": "2024-06-03T02:00:00+02:00"}, wantIDs: []int64{2}},
+		{name: "no zone means UTC", args: map[string]any{"until": "2024-06-02T23:59:59"}, wantIDs: []int64{1}},
+		{name: "fractional seconds", args: map[string]any{"since": "2024-06-09T23:59:59.999Z"}, wantIDs: []int64{4, 5}},
+		{name: "blank values ignored", args: map[string]any{"since": " ", "until": ""}, wantIDs: []int64{1, 2, 3, 4, 5}},
+		{name: "invalid since", args: map[string]any{"since": "last week"}, wantErr: `invalid params: invalid since "last week": expected an ISO-8601 timestamp`},
+		{name: "invalid until", args: map[string]any{"until": "2024-13-01"}, wantErr: `invalid params: invalid until "2024-13-01"`},
+		{name: "inverted range", args: map[string]any{"since": "2024-06-10", "until": "2024-06-03"}, wantErr: "invalid params: since 2024-06-10T00:00:00.000Z must be before until 2024-06-03T00:00:00.000Z"},
+		{name: "empty range", args: map[string]any{"since": "2024-06-03", "until": "2024-06-03T00:00:00Z"}, wantErr: "must be before until"},
+	}
+	s := newTestServer(t, DefaultConfig())
+	seed := []any{}
+	for _, createdAt := range []string{"2024-06-01T12:00:00Z", "2024-06-02T23:59:59.500Z", "2024-06-03T00:00:00Z", "2024-06-09T23:59:59.999Z", "2024-06-10T00:00:00Z"} {
+		seed = append(seed, map[string]any{"content": "created " + createdAt, "created_at": createdAt})
+	}
+	if out, isErr := callTool(t, s.SimpleMemoryImport, map[string]any{"memories": seed}); isErr {
+		t.Fatalf("import: %s", out)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemoryList, tt.args)
+			if tt.wantErr != "" {
+				if !isErr || !strings.Contains(out, tt.wantErr) {
+					t.Fatalf("got %q (error=%v), want an error containing %q", out, isErr, tt.wantErr)
+				}
+				return
+			}
+			var page memoryPage
+			if isErr || json.Unmarshal([]byte(out), &page) != nil {
+				t.Fatalf("list: %s", out)
+			}
+			if ids := memoryIDs(page.Memories); !equalIDs(ids, tt.wantIDs) {
+				t.Fatalf("ids %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestListOrder(t *testing.T) {
+	seed := func(t *testing.T, cfg Config) *SimpleMemoryServer {
+		s := newTestServer(t, cfg)
+		mustAdd(t, s, map[string]any{"memory": "one"}, map[string]any{"memory": "two"}, map[string]any{"memory": "three"})
+		// Memory 2 is the newest and memories 1 and 3 tie, so IDs must break the tie.
+		for id, created := range map[int64]string{1: "2024-01-01 00:00:00", 2: "2024-03-01 00:00:00", 3: "2024-01-01 00:00:00"} {
+			if _, err := s.db.Exec("UPDATE "+s.table+" SET created_at = ? WHERE id = ?", created, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return s
+	}
+	tests := []struct {
+		order   string
+		args    map[string]any
+		wantIDs []int64
+	}{
+		{order: "", wantIDs: []int64{1, 2, 3}},
+		{order: "oldest", wantIDs: []int64{1, 2, 3}},
+		{order: "recent", wantIDs: []int64{2, 3, 1}},
+		{order: "recent", args: map[string]any{"limit": 2, "offset": 1}, wantIDs: []int64{3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.ListOrder = tt.order
+			out, isErr := callTool(t, seed(t, cfg).SimpleMemoryList, tt.args)
+			if isErr {
+				t.Fatalf("list: %s", out)
+			}
+			var page memoryPage
+			if err := json.Unmarshal([]byte(out), &page); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if ids := memoryIDs(page.Memories); !equalIDs(ids, tt.wantIDs) {
+				t.Fatalf("got ids %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+
+	db, err := sql.Open(DriverName, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cfg := DefaultConfig()
+	cfg.ListOrder = "priority"
+	if _, err := New(db, cfg); err == nil || !strings.Contains(err.Error(), "invalid default order") {
+		t.Fatalf("New with unknown order: got %v, want invalid default order error", err)
+	}
+}
+
+func TestSimpleMemorySearch(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func(*Config)
+		seed []map[string]any
+		// like forces substring matching even when the build has FTS5.
+		like    bool
+		query   string
+		wantIDs []int64
+		// wantText is the whole response when nothing matches.
+		wantText string
+		wantErr  bool
+	}{
+		{
+			name:    "ranks title above content",
+			seed:    []map[string]any{{"memory": "uses the chi router"}, {"memory": "http stack", "title": "Router choice"}},
+			query:   "router",
+			wantIDs: []int64{2, 1},
+		},
+		{
+			name:    "content weight above title flips the order",
+			cfg:     func(c *Config) { c.Weights = SearchWeights{Title: 1, Tags: 1, Status: 1, Content: 5} },
+			seed:    []map[string]any{{"memory": "uses the chi router"}, {"memory": "http stack", "title": "Router choice"}},
+			query:   "router",
+			wantIDs: []int64{1, 2},
+		},
+		{
+			name:    "substring ranks title above content",
+			like:    true,
+			seed:    []map[string]any{{"memory": "uses the chi router"}, {"memory": "http stack", "title": "Router choice"}},
+			query:   "router",
+			wantIDs: []int64{2, 1},
+		},
+		{
+			name:    "substring content weight above title flips the order",
+			cfg:     func(c *Config) { c.Weights = SearchWeights{Title: 1, Tags: 1, Status: 1, Content: 5} },
+			like:    true,
+			seed:    []map[string]any{{"memory": "uses the chi router"}, {"memory": "http stack", "title": "Router choice"}},
+			query:   "router",
+			wantIDs: []int64{1, 2},
+		},
+		{
+			name:    "substring tag weight outranks title and content",
+			cfg:     func(c *Config) { c.Weights = SearchWeights{Title: 1, Tags: 4, Status: 1, Content: 1} },
+			like:    true,
+			seed:    []map[string]any{{"memory": "deploy notes", "title": "deploy"}, {"memory": "other", "tags": "deploy"}},
+			query:   "deploy",
+			wantIDs: []int64{2, 1},
+		},
+		{
+			name:     "no match",
+			seed:     []map[string]any{{"memory": "something"}},
+			query:    "absent",
+			wantText: "No matching simple-memories found.",
+		},
+		{
+			name:     "structured empty result",
+			cfg:      func(c *Config) { c.StructuredResults = true },
+			seed:     []map[string]any{{"memory": "something"}},
+			query:    "absent",
+			wantText: `{"results":[],"count":0}`,
+		},
+		{name: "empty query", query: "  ", wantErr: true},
+		{
+			name:    "turkish dotted capital I",
+			cfg:     func(c *Config) { c.UnicodeFold = true },
+			seed:    []map[string]any{{"memory": "İSTANBUL trip"}, {"memory": "ankara"}},
+			query:   "istanbul",
+			wantIDs: []int64{1},
+		},
+		{
+			name:    "greek final sigma",
+			cfg:     func(c *Config) { c.UnicodeFold = true },
+			seed:    []map[string]any{{"memory": "ΟΔΟΣ Αθηνάς"}, {"memory": "other"}},
+			query:   "οδος",
+			wantIDs: []int64{1},
+		},
+		{
+			name:    "greek accented title",
+			cfg:     func(c *Config) { c.UnicodeFold = true },
+			seed:    []map[string]any{{"memory": "notes", "title": "ΣΟΦΊΑ"}},
+			query:   "σοφία",
+			wantIDs: []int64{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			s := newTestServer(t, cfg)
+			if tt.like {
+				s.fts = false
+			}
+			mustAdd(t, s, tt.seed...)
+			out, isErr := callTool(t, s.SimpleMemorySearch, map[string]any{"query": tt.query})
+			switch {
+			case tt.wantErr:
+				if !isErr {
+					t.Fatalf("got %q, want error", out)
+				}
+				return
+			case isErr:
+				t.Fatalf("search: %s", out)
+			case tt.wantText != "":
+				if out != tt.wantText {
+					t.Fatalf("got %q, want %q", out, tt.wantText)
+				}
+				return
+			}
+			var matches []Memory
+			if err := json.Unmarshal([]byte(out), &matches); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if ids := memoryIDs(matches); !equalIDs(ids, tt.wantIDs) {
+				t.Fatalf("got ids %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name string
+		// like forces substring matching even when the build has FTS5.
+		like    bool
+		search  bool
+		args    map[string]any
+		wantSQL string
+	}{
+		{
+			name:    "list",
+			args:    map[string]any{"tags": "go", "limit": 2, "offset": 1},
+			wantSQL: "SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT) FROM simple_memories m WHERE ",
+		},
+		{
+			name:    "list everything",
+			args:    map[string]any{"limit": 0},
+			wantSQL: "SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT) FROM simple_memories m ORDER BY m.id ASC LIMIT ? OFFSET ?",
+		},
+		{
+			name:    "search",
+			search:  true,
+			args:    map[string]any{"query": "api", "status": "open"},
+			wantSQL: "SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT) FROM simple_memories m ",
+		},
+		{
+			name:    "substring search",
+			like:    true,
+			search:  true,
+			args:    map[string]any{"query": "api", "tags": "go"},
+			wantSQL: "SELECT m.id, m.title, m.tags, m.status, m.content, CAST(m.created_at AS TEXT) FROM simple_memories m WHERE (m.title LIKE ? OR ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, DefaultConfig())
+			if tt.like {
+				s.fts = false
+			}
+			mustAdd(t, s,
+				map[string]any{"memory": "chi router for the api", "tags": "go", "status": "open"},
+				map[string]any{"memory": "api gateway", "tags": "ops", "status": "open"},
+				map[string]any{"memory": "pgx driver", "tags": "go"},
+				map[string]any{"memory": "api client", "tags": "go, http", "status": "open"},
+				map[string]any{"memory": "api docs", "tags": "go", "status": "done"},
+			)
+			handler := s.SimpleMemoryList
+			if tt.search {
+				handler = s.SimpleMemorySearch
+			}
+			want, isErr := callTool(t, handler, tt.args)
+			if isErr {
+				t.Fatalf("run: %s", want)
+			}
+			var wantIDs []int64
+			if tt.search {
+				var memories []Memory
+				if err := json.Unmarshal([]byte(want), &memories); err != nil {
+					t.Fatalf("decode %q: %v", want, err)
+				}
+				wantIDs = memoryIDs(memories)
+			} else {
+				var page memoryPage
+				if err := json.Unmarshal([]byte(want), &page); err != nil {
+					t.Fatalf("decode %q: %v", want, err)
+				}
+				wantIDs = memoryIDs(page.Memories)
+			}
+
+			args := maps.Clone(tt.args)
+			args["explain"] = true
+			out, isErr := callTool(t, handler, args)
+			if isErr {
+				t.Fatalf("explain: %s", out)
+			}
+			var explained struct {
+				SQL       string   `json:"sql"`
+				Args      []any    `json:"args"`
+				QueryPlan []string `json:"query_plan"`
+			}
+			if err := json.Unmarshal([]byte(out), &explained); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if !strings.HasPrefix(explained.SQL, tt.wantSQL) || len(explained.QueryPlan) == 0 {
+				t.Fatalf("explained %s, want sql starting %q and a query plan", out, tt.wantSQL)
+			}
+			if tt.search && s.fts && !strings.Contains(explained.SQL, "FROM simple_memories m JOIN simple_memories_fts ON simple_memories_fts.rowid = m.id WHERE simple_memories_fts MATCH ?") {
+				t.Fatalf("explained %s, want the full-text join", explained.SQL)
+			}
+			// Running the explained statement must give exactly what the tool returned.
+			for i, arg := range explained.Args {
+				if f, ok := arg.(float64); ok && f == float64(int64(f)) {
+					explained.Args[i] = int64(f)
+				}
+			}
+			rows, err := s.db.Query(explained.SQL, explained.Args...)
+			if err != nil {
+				t.Fatalf("run explained sql: %v", err)
+			}
+			defer rows.Close()
+			memories, err := scanMemories(rows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := memoryIDs(memories); len(wantIDs) == 0 || !equalIDs(got, wantIDs) {
+				t.Fatalf("explained sql returns %v, tool returned %v", got, wantIDs)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryFilterIDs(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "one", "tags": "work", "status": "open"},
+		map[string]any{"memory": "two", "status": "done"},
+		map[string]any{"memory": "three", "tags": "work", "status": "open"},
+		map[string]any{"memory": "four", "status": "open"},
+	)
+	tests := []struct {
+		name    string
+		args    map[string]any
+		wantIDs []int64
+	}{
+		{name: "ids only keep given order", args: map[string]any{"ids": []any{3, 1, 2}}, wantIDs: []int64{3, 1, 2}},
+		{name: "ids and status", args: map[string]any{"ids": []any{4, 2, 1}, "status": "open"}, wantIDs: []int64{4, 1}},
+		{name: "ids, status, and tags", args: map[string]any{"ids": []any{1, 2, 3, 4}, "status": "open", "tags": "work"}, wantIDs: []int64{1, 3}},
+		{name: "ids and query", args: map[string]any{"ids": []any{1, 2, 3}, "query": "three"}, wantIDs: []int64{3}},
+		{name: "unknown and duplicate ids", args: map[string]any{"ids": []any{9, 2, 2}}, wantIDs: []int64{2}},
+		{name: "no intersection", args: map[string]any{"ids": []any{2}, "status": "open"}, wantIDs: []int64{}},
+		{name: "empty ids", args: map[string]any{"ids": []any{}}, wantIDs: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemoryFilterIDs, tt.args)
+			if isErr {
+				t.Fatalf("filter ids: %s", out)
+			}
+			if len(tt.wantIDs) == 0 {
+				if out != "No matching simple-memories found." {
+					t.Fatalf("got %q, want no matches", out)
+				}
+				return
+			}
+			var memories []Memory
+			if err := json.Unmarshal([]byte(out), &memories); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if ids := memoryIDs(memories); !equalIDs(ids, tt.wantIDs) {
+				t.Fatalf("ids %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+	if out, isErr := callTool(t, s.SimpleMemoryFilterIDs, map[string]any{"status": "open"}); !isErr {
+		t.Fatalf("missing ids: got %q, want error", out)
+	}
+}
+
+func TestSimpleMemoryDelete(t *testing.T) {
+	seed := []map[string]any{
+		{"memory": "learn golang"},               // 1: substring of a content word
+		{"memory": "database", "tags": "mongo"},  // 2: substring of a tag
+		{"memory": "plan", "title": "Go-live"},   // 3: title, different case
+		{"memory": "plan", "status": "ongoing"},  // 4: status
+		{"memory": "unrelated"},                  // 5
+		{"memory": "100% done"},                  // 6
+		{"memory": "Ünïcödé ☕", "tags": "emoji"}, // 7
+	}
+	tests := []struct {
+		name      string
+		query     string
+		wantText  string
+		wantIDs   []int64
+		wantError bool
+	}{
+		{name: "matches substrings in every field", query: "go", wantText: "Deleted 4 simple-memories.", wantIDs: []int64{5, 6, 7}},
+		{name: "no match", query: "absent", wantText: "No simple-memories deleted (no match).", wantIDs: []int64{1, 2, 3, 4, 5, 6, 7}},
+		{name: "unicode", query: "☕", wantText: "Deleted 1 simple-memories.", wantIDs: []int64{1, 2, 3, 4, 5, 6}},
+		{name: "percent is a wildcard", query: "%", wantText: "Deleted 7 simple-memories.", wantIDs: []int64{}},
+		{name: "empty query", query: " ", wantError: true, wantIDs: []int64{1, 2, 3, 4, 5, 6, 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, DefaultConfig())
+			mustAdd(t, s, seed...)
+			out, isErr := callTool(t, s.SimpleMemoryDelete, map[string]any{"query": tt.query})
+			if isErr != tt.wantError || (!tt.wantError && out != tt.wantText) {
+				t.Fatalf("got %q (error=%v), want %q (error=%v)", out, isErr, tt.wantText, tt.wantError)
+			}
+			if ids := listIDs(t, s); !equalIDs(ids, tt.wantIDs) {
+				t.Fatalf("remaining ids %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryExtremes(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "medium note", "tags": "work"},        // 1: 11 characters
+		map[string]any{"memory": "ok"},                                 // 2: 2
+		map[string]any{"memory": strings.Repeat("x", 40)},              // 3: 40
+		map[string]any{"memory": "日本語", "tags": "work"},                // 4: 3 characters, 9 bytes
+		map[string]any{"memory": "a longer work note", "tags": "work"}, // 5: 18
+	)
+	tests := []struct {
+		name         string
+		args         map[string]any
+		wantLongest  []int64
+		wantShortest []int64
+		wantLengths  map[int64]int
+	}{
+		{
+			name:         "all",
+			args:         map[string]any{"limit": 2},
+			wantLongest:  []int64{3, 5},
+			wantShortest: []int64{2, 4},
+			wantLengths:  map[int64]int{2: 2, 3: 40, 4: 3, 5: 18},
+		},
+		{
+			name:         "filtered",
+			args:         map[string]any{"tags": "work", "limit": 1},
+			wantLongest:  []int64{5},
+			wantShortest: []int64{4},
+			wantLengths:  map[int64]int{4: 3, 5: 18},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemoryExtremes, tt.args)
+			if isErr {
+				t.Fatalf("extremes: %s", out)
+			}
+			type sized struct {
+				ID     int64 `json:"id"`
+				Length int   `json:"length"`
+			}
+			var result struct {
+				Longest  []sized `json:"longest"`
+				Shortest []sized `json:"shortest"`
+			}
+			if err := json.Unmarshal([]byte(out), &result); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			for _, end := range []struct {
+				got  []sized
+				want []int64
+			}{{result.Longest, tt.wantLongest}, {result.Shortest, tt.wantShortest}} {
+				var ids []int64
+				for _, m := range end.got {
+					ids = append(ids, m.ID)
+					if m.Length != tt.wantLengths[m.ID] {
+						t.Fatalf("memory %d length %d, want %d", m.ID, m.Length, tt.wantLengths[m.ID])
+					}
+				}
+				if !equalIDs(ids, end.want) {
+					t.Fatalf("got ids %v, want %v in %s", ids, end.want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteConfirmation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.DeleteConfirmTTL = time.Minute
+	s := newTestServer(t, cfg)
+	mustAdd(t, s,
+		map[string]any{"memory": "old plan"},
+		map[string]any{"memory": "keep"},
+		map[string]any{"memory": "old idea"},
+	)
+	issue := func(handler server.ToolHandlerFunc, args map[string]any) string {
+		t.Helper()
+		out, isErr := callTool(t, handler, args)
+		if isErr {
+			t.Fatalf("issue token: %s", out)
+		}
+		var pending struct {
+			ConfirmToken string  `json:"confirm_token"`
+			IDs          []int64 `json:"ids"`
+		}
+		if err := json.Unmarshal([]byte(out), &pending); err != nil || pending.ConfirmToken == "" {
+			t.Fatalf("got %q, want a confirm_token", out)
+		}
+		return pending.ConfirmToken
+	}
+	deleteWith := func(handler server.ToolHandlerFunc, args map[string]any, wantErr string) {
+		t.Helper()
+		out, isErr := callTool(t, handler, args)
+		if (wantErr == "" && isErr) || (wantErr != "" && (!isErr || !strings.Contains(out, wantErr))) {
+			t.Fatalf("got %q (error=%v), want error containing %q", out, isErr, wantErr)
+		}
+	}
+
+	token := issue(s.SimpleMemoryDelete, map[string]any{"query": "old"})
+	if ids := listIDs(t, s); !equalIDs(ids, []int64{1, 2, 3}) {
+		t.Fatalf("issuing a token deleted memories: remaining %v", ids)
+	}
+	deleteWith(s.SimpleMemoryDelete, map[string]any{"query": "plan", "confirm_token": token}, "does not match")
+	deleteWith(s.SimpleMemoryDelete, map[string]any{"query": "old", "confirm_token": token}, "unknown, expired, or already used")
+
+	// The matching memories changed after the token was issued.
+	token = issue(s.SimpleMemoryDelete, map[string]any{"query": "old"})
+	mustAdd(t, s, map[string]any{"memory": "old news"})
+	deleteWith(s.SimpleMemoryDelete, map[string]any{"query": "old", "confirm_token": token}, "does not match")
+
+	token = issue(s.SimpleMemoryDelete, map[string]any{"query": "old"})
+	deleteWith(s.SimpleMemoryDelete, map[string]any{"query": "old", "confirm_token": token}, "")
+	if ids := listIDs(t, s); !equalIDs(ids, []int64{2}) {
+		t.Fatalf("remaining ids %v, want [2]", ids)
+	}
+
+	token = issue(s.SimpleMemoryDeleteByID, map[string]any{"id": 2})
+	s.deleteConfirmTTL = time.Millisecond
+	expired := issue(s.SimpleMemoryDeleteByID, map[string]any{"id": 2})
+	time.Sleep(5 * time.Millisecond)
+	deleteWith(s.SimpleMemoryDeleteByID, map[string]any{"id": 2, "confirm_token": expired}, "unknown, expired, or already used")
+	deleteWith(s.SimpleMemoryDeleteByID, map[string]any{"id": 2, "confirm_token": token}, "")
+	if ids := listIDs(t, s); len(ids) != 0 {
+		t.Fatalf("remaining ids %v, want none", ids)
+	}
+}
+
+func TestStructuredResults(t *testing.T) {
+	seed := []map[string]any{
+		{"memory": "deploy notes for the api server", "tags": "ops", "status": "open", "remind_at": "2020-01-01"},
+		{"memory": "cafÃ© menu", "tags": "food"},
+		{"memory": "deploy checklist", "tags": "ops"},
+	}
+	tests := []struct {
+		name      string
+		tool      func(*SimpleMemoryServer) server.ToolHandlerFunc
+		seed      []map[string]any
+		args      map[string]any
+		wantCount int
+		// wantIDs, when set, are the IDs of the results, or the results themselves if numbers.
+		wantIDs []int64
+		// wantFields are the tool-specific fields that follow results and count.
+		wantFields map[string]string
+	}{
+		{
+			name:       "list",
+			tool:       func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryList },
+			args:       map[string]any{"limit": 2},
+			wantCount:  2,
+			wantIDs:    []int64{1, 2},
+			wantFields: map[string]string{"total": "3", "limit": "2", "offset": "0"},
+		},
+		{
+			name:       "list empty",
+			tool:       func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryList },
+			args:       map[string]any{"tags": "missing"},
+			wantFields: map[string]string{"total": "0", "limit": "50", "offset": "0"},
+		},
+		{
+			name:      "search",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemorySearch },
+			args:      map[string]any{"query": "checklist"},
+			wantCount: 1,
+			wantIDs:   []int64{3},
+		},
+		{
+			name: "search empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemorySearch },
+			args: map[string]any{"query": "absent"},
+		},
+		{
+			name:      "filter ids",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryFilterIDs },
+			args:      map[string]any{"ids": []any{float64(3), float64(1)}},
+			wantCount: 2,
+			wantIDs:   []int64{3, 1},
+		},
+		{
+			name: "filter ids empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryFilterIDs },
+			args: map[string]any{"ids": []any{float64(9)}},
+		},
+		{
+			name:      "extremes",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryExtremes },
+			args:      map[string]any{"limit": 1},
+			wantCount: 2,
+			wantIDs:   []int64{1, 2},
+		},
+		{
+			name: "extremes empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryExtremes },
+			args: map[string]any{"query": "absent"},
+		},
+		{
+			name:       "count",
+			tool:       func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryCount },
+			wantCount:  2,
+			wantFields: map[string]string{"total": "3"},
+		},
+		{
+			name:       "count empty",
+			tool:       func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryCount },
+			args:       map[string]any{"query": "absent"},
+			wantFields: map[string]string{"total": "0"},
+		},
+		{
+			name:      "trends",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryTrends },
+			args:      map[string]any{"group_by": "status", "bucket": "month"},
+			wantCount: 2,
+		},
+		{
+			name: "trends empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryTrends },
+			seed: []map[string]any{},
+		},
+		{
+			name:      "similar",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemorySimilar },
+			args:      map[string]any{"id": 1, "top_k": 1},
+			wantCount: 1,
+			wantIDs:   []int64{3},
+		},
+		{
+			name: "similar empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemorySimilar },
+			seed: []map[string]any{{"memory": "alone"}},
+			args: map[string]any{"id": 1},
+		},
+		{
+			name:      "due reminders",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDueReminders },
+			args:      map[string]any{"as_of": "2021-01-01"},
+			wantCount: 1,
+			wantIDs:   []int64{1},
+		},
+		{
+			name: "due reminders empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDueReminders },
+			args: map[string]any{"as_of": "2019-01-01"},
+		},
+		{
+			name:      "find mojibake",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryFindMojibake },
+			wantCount: 1,
+			wantIDs:   []int64{2},
+		},
+		{
+			name: "find mojibake empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryFindMojibake },
+			seed: []map[string]any{{"memory": "café menu"}},
+		},
+		{
+			name:       "digest",
+			tool:       func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDigest },
+			args:       map[string]any{"query": "deploy", "dry_run": true},
+			wantCount:  1,
+			wantIDs:    []int64{0},
+			wantFields: map[string]string{"dry_run": "true", "source_ids": "[1,3]", "archived": "0"},
+		},
+		{
+			name: "digest empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDigest },
+			args: map[string]any{"query": "absent"},
+		},
+		{
+			name:      "delete",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDelete },
+			args:      map[string]any{"query": "deploy"},
+			wantCount: 2,
+			wantIDs:   []int64{1, 3},
+		},
+		{
+			name: "delete empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDelete },
+			args: map[string]any{"query": "absent"},
+		},
+		{
+			name:      "delete by id",
+			tool:      func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDeleteByID },
+			args:      map[string]any{"id": 2},
+			wantCount: 1,
+			wantIDs:   []int64{2},
+		},
+		{
+			name: "delete by id empty",
+			tool: func(s *SimpleMemoryServer) server.ToolHandlerFunc { return s.SimpleMemoryDeleteByID },
+			args: map[string]any{"id": 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.StructuredResults = true
+			s := newTestServer(t, cfg)
+			if tt.seed == nil {
+				tt.seed = seed
+			}
+			mustAdd(t, s, tt.seed...)
+			out, isErr := callTool(t, tt.tool(s), tt.args)
+			if isErr {
+				t.Fatalf("tool error: %s", out)
+			}
+			var envelope map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(out), &envelope); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			var results []json.RawMessage
+			if err := json.Unmarshal(envelope["results"], &results); err != nil || results == nil {
+				t.Fatalf("results in %s: not an array (%v)", out, err)
+			}
+			if count := string(envelope["count"]); count != strconv.Itoa(tt.wantCount) || len(results) != tt.wantCount {
+				t.Fatalf("count %s for %d results in %s, want %d", count, len(results), out, tt.wantCount)
+			}
+			if tt.wantIDs != nil {
+				ids := []int64{}
+				for _, r := range results {
+					var item struct {
+						ID int64 `json:"id"`
+					}
+					if err := json.Unmarshal(r, &item.ID); err != nil {
+						if err := json.Unmarshal(r, &item); err != nil {
+							t.Fatal(err)
+						}
+					}
+					ids = append(ids, item.ID)
+				}
+				if !equalIDs(ids, tt.wantIDs) {
+					t.Fatalf("result ids %v, want %v in %s", ids, tt.wantIDs, out)
+				}
+			}
+			for field, want := range tt.wantFields {
+				if got := string(envelope[field]); got != want {
+					t.Fatalf("%s = %s, want %s in %s", field, got, want, out)
+				}
+			}
+			if len(envelope) != 2+len(tt.wantFields) {
+				t.Fatalf("got fields %v, want results, count and %v", slices.Sorted(maps.Keys(envelope)), slices.Sorted(maps.Keys(tt.wantFields)))
+			}
+		})
+	}
+}
+
+func TestLogQuotesContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		memory  string
+		wantLog string
+	}{
+		{name: "printable unicode is kept", memory: "café ☕ 日本", wantLog: `content="café ☕ 日本"`},
+		{name: "control characters are escaped", memory: "line1\nline2\tend", wantLog: `content="line1\nline2\tend"`},
+		{name: "quotes are escaped", memory: `say "hi"`, wantLog: `content="say \"hi\""`},
+		{name: "invalid utf-8 is escaped", memory: "bad \xff byte", wantLog: `content="bad \xff byte"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := DefaultConfig()
+			cfg.Logger = log.New(&buf, "", 0)
+			s := newTestServer(t, cfg)
+			mustAdd(t, s, map[string]any{"memory": tt.memory})
+			if !strings.Contains(buf.String(), tt.wantLog) {
+				t.Fatalf("log %q does not contain %q", buf.String(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestComposeDigest(t *testing.T) {
+	got := composeDigest([]Memory{
+		{ID: 3, Content: "first note"},
+		{ID: 7, Title: "Chi", Content: "second note"},
+	})
+	want := "Digest of 2 simple-memories: #3, #7\n\n[#3]\nfirst note\n\n[#7] Chi\nsecond note"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSimpleMemoryDigest(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         map[string]any
+		wantIDs      []int64
+		wantStatuses map[int64]string
+	}{
+		{
+			name:         "dry run changes nothing",
+			args:         map[string]any{"tags": "go", "dry_run": true},
+			wantIDs:      []int64{1, 2, 3},
+			wantStatuses: map[int64]string{1: "", 2: "", 3: ""},
+		},
+		{
+			name:         "keeps originals",
+			args:         map[string]any{"tags": "go"},
+			wantIDs:      []int64{1, 2, 3, 4},
+			wantStatuses: map[int64]string{1: "", 2: "", 3: "", 4: ""},
+		},
+		{
+			name:         "archives originals",
+			args:         map[string]any{"tags": "go", "archive": true},
+			wantIDs:      []int64{1, 2, 3, 4},
+			wantStatuses: map[int64]string{1: archivedStatus, 2: "", 3: archivedStatus, 4: ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, DefaultConfig())
+			mustAdd(t, s,
+				map[string]any{"memory": "use chi", "tags": "go"},
+				map[string]any{"memory": "use psql", "tags": "db"},
+				map[string]any{"memory": "go 1.24", "tags": "go, tooling"},
+			)
+			out, isErr := callTool(t, s.SimpleMemoryDigest, tt.args)
+			if isErr {
+				t.Fatalf("digest: %s", out)
+			}
+			var result struct {
+				Digest    Memory  `json:"digest"`
+				SourceIDs []int64 `json:"source_ids"`
+			}
+			if err := json.Unmarshal([]byte(out), &result); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if !equalIDs(result.SourceIDs, []int64{1, 3}) {
+				t.Fatalf("source ids %v, want [1 3]", result.SourceIDs)
+			}
+			if want := "Digest of 2 simple-memories: #1, #3\n\n[#1]\nuse chi\n\n[#3]\ngo 1.24"; result.Digest.Content != want {
+				t.Fatalf("digest content %q, want %q", result.Digest.Content, want)
+			}
+			if ids := listIDs(t, s); !equalIDs(ids, tt.wantIDs) {
+				t.Fatalf("ids %v, want %v", ids, tt.wantIDs)
+			}
+			rows, err := s.db.Query("SELECT id, COALESCE(status, '') FROM simple_memories")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			got := map[int64]string{}
+			for rows.Next() {
+				var (
+					id     int64
+					status string
+				)
+				if err := rows.Scan(&id, &status); err != nil {
+					t.Fatal(err)
+				}
+				got[id] = status
+			}
+			if len(got) != len(tt.wantStatuses) {
+				t.Fatalf("statuses %v, want %v", got, tt.wantStatuses)
+			}
+			for id, status := range tt.wantStatuses {
+				if got[id] != status {
+					t.Fatalf("statuses %v, want %v", got, tt.wantStatuses)
+				}
+			}
+		})
+	}
+}
+
+func TestDigestContentLimits(t *testing.T) {
+	// The digest of the two seeded memories is 63 bytes.
+	tests := []struct {
+		name     string
+		maxBytes int
+		minChars int
+		args     map[string]any
+		wantErr  string
+	}{
+		{name: "within limits", maxBytes: 63, minChars: 63, args: map[string]any{"tags": "go"}},
+		{name: "over max", maxBytes: 62, args: map[string]any{"tags": "go"}, wantErr: "memory exceeds max size of 62 bytes"},
+		{name: "dry run over max", maxBytes: 62, args: map[string]any{"tags": "go", "dry_run": true}, wantErr: "memory exceeds max size of 62 bytes"},
+		{name: "under min", minChars: 64, args: map[string]any{"tags": "go", "archive": true}, wantErr: "memory is too short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, DefaultConfig())
+			mustAdd(t, s,
+				map[string]any{"memory": "use chi", "tags": "go"},
+				map[string]any{"memory": "go 1.24", "tags": "go, tooling"},
+			)
+			s.maxContentBytes, s.minContentChars = tt.maxBytes, tt.minChars
+			out, isErr := callTool(t, s.SimpleMemoryDigest, tt.args)
+			if tt.wantErr == "" {
+				if isErr {
+					t.Fatalf("digest: %s", out)
+				}
+				if ids := listIDs(t, s); !equalIDs(ids, []int64{1, 2, 3}) {
+					t.Fatalf("ids %v, want the digest added as 3", ids)
+				}
+				return
+			}
+			if !isErr || !strings.Contains(out, tt.wantErr) {
+				t.Fatalf("got %q (error=%v), want an error containing %q", out, isErr, tt.wantErr)
+			}
+			if ids := listIDs(t, s); !equalIDs(ids, []int64{1, 2}) {
+				t.Fatalf("rejected digest left ids %v, want [1 2]", ids)
+			}
+			if out, _ := callTool(t, s.SimpleMemoryCount, map[string]any{"status": archivedStatus}); !strings.Contains(out, `"total":0`) {
+				t.Fatalf("rejected digest archived its sources: %s", out)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryTransform(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        map[string]any
+		wantChanged int
+		wantPreview string
+		want        map[int64]string
+	}{
+		{
+			name:        "prepends header",
+			wantPreview: "## Go\nuse chi",
+			args:        map[string]any{"tags": "go", "template": "## Go\n{{.Content}}"},
+			wantChanged: 2,
+			want:        map[int64]string{1: "## Go\nuse chi", 2: "use psql", 3: "## Go\ngo 1.24"},
+		},
+		{
+			name:        "find and replace",
+			wantPreview: "prefer chi",
+			args:        map[string]any{"query": "use", "find": "use", "replace": "prefer"},
+			wantChanged: 2,
+			want:        map[int64]string{1: "prefer chi", 2: "prefer psql", 3: "go 1.24"},
+		},
+		{
+			name:        "dry run changes nothing",
+			wantPreview: "## Go\nuse chi",
+			args:        map[string]any{"tags": "go", "template": "## Go\n{{.Content}}", "dry_run": true},
+			wantChanged: 2,
+			want:        map[int64]string{1: "use chi", 2: "use psql", 3: "go 1.24"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, DefaultConfig())
+			mustAdd(t, s,
+				map[string]any{"memory": "use chi", "tags": "go"},
+				map[string]any{"memory": "use psql", "tags": "db"},
+				map[string]any{"memory": "go 1.24", "tags": "go, tooling"},
+			)
+			out, isErr := callTool(t, s.SimpleMemoryTransform, tt.args)
+			if isErr {
+				t.Fatalf("transform: %s", out)
+			}
+			var result struct {
+				Changed int `json:"changed"`
+				Preview []struct {
+					ID    int64  `json:"id"`
+					After string `json:"after"`
+				} `json:"preview"`
+			}
+			if err := json.Unmarshal([]byte(out), &result); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if result.Changed != tt.wantChanged || len(result.Preview) != tt.wantChanged {
+				t.Fatalf("changed %d with %d previews, want %d", result.Changed, len(result.Preview), tt.wantChanged)
+			}
+			if first := result.Preview[0]; first.ID != 1 || first.After != tt.wantPreview {
+				t.Fatalf("preview %+v, want #1 %q", first, tt.wantPreview)
+			}
+			for id, want := range tt.want {
+				var content string
+				if err := s.db.QueryRow("SELECT content FROM simple_memories WHERE id = ?", id).Scan(&content); err != nil {
+					t.Fatal(err)
+				}
+				if content != want {
+					t.Fatalf("memory %d content %q, want %q", id, content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryTagMatches(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "chi router notes", "tags": "go"},
+		map[string]any{"memory": "router config", "tags": "Review, ops"},
+		map[string]any{"memory": "pgx driver", "tags": "go"},
+	)
+	type result struct {
+		DryRun  bool    `json:"dry_run"`
+		Matched int     `json:"matched"`
+		Changed int     `json:"changed"`
+		IDs     []int64 `json:"ids"`
+	}
+	tag := func(args map[string]any) result {
+		t.Helper()
+		out, isErr := callTool(t, s.SimpleMemoryTagMatches, args)
+		if isErr {
+			t.Fatalf("tag matches %v: %s", args, out)
+		}
+		var r result
+		if err := json.Unmarshal([]byte(out), &r); err != nil {
+			t.Fatalf("decode %q: %v", out, err)
+		}
+		return r
+	}
+	tagsOf := func(id int64) string {
+		t.Helper()
+		var tags string
+		if err := s.db.QueryRow("SELECT tags FROM "+s.table+" WHERE id = ?", id).Scan(&tags); err != nil {
+			t.Fatal(err)
+		}
+		return tags
+	}
+
+	preview := tag(map[string]any{"query": "router", "add_tags": "review", "dry_run": true})
+	if want := (result{DryRun: true, Matched: 2, Changed: 1, IDs: []int64{1, 2}}); mustJSON(t, preview) != mustJSON(t, want) {
+		t.Fatalf("dry run: got %+v, want %+v", preview, want)
+	}
+	if got := tagsOf(1); got != "go" {
+		t.Fatalf("dry run wrote tags %q", got)
+	}
+
+	// A memory that starts matching after the preview is not tagged when the preview's IDs are applied.
+	mustAdd(t, s, map[string]any{"memory": "router added later"})
+	ids := make([]any, len(preview.IDs))
+	for i, id := range preview.IDs {
+		ids[i] = float64(id)
+	}
+	applied := tag(map[string]any{"query": "router", "add_tags": "review, http", "ids": ids})
+	if want := (result{Matched: 2, Changed: 2, IDs: []int64{1, 2}}); mustJSON(t, applied) != mustJSON(t, want) {
+		t.Fatalf("apply: got %+v, want %+v", applied, want)
+	}
+	for id, want := range map[int64]string{1: "go,review,http", 2: "Review,ops,http", 3: "go", 4: ""} {
+		if got := tagsOf(id); got != want {
+			t.Fatalf("memory %d tags %q, want %q", id, got, want)
+		}
+	}
+
+	again := tag(map[string]any{"query": "router", "add_tags": "review", "untagged": true})
+	if want := (result{Matched: 1, Changed: 1, IDs: []int64{4}}); mustJSON(t, again) != mustJSON(t, want) {
+		t.Fatalf("filtered: got %+v, want %+v", again, want)
+	}
+	for name, args := range map[string]map[string]any{
+		"empty add_tags": {"query": "router", "add_tags": " , "},
+		"empty ids":      {"query": "router", "add_tags": "x", "ids": []any{}},
+	} {
+		if out, isErr := callTool(t, s.SimpleMemoryTagMatches, args); !isErr {
+			t.Fatalf("%s: got %q, want an error", name, out)
+		}
+	}
+}
+
+func TestTableName(t *testing.T) {
+	tests := []struct {
+		namespace string
+		want      string
+		wantErr   string
+	}{
+		{namespace: "", want: "simple_memories"},
+		{namespace: "   ", want: "simple_memories"},
+		{namespace: "projectx", want: "simple_memories_projectx"},
+		{namespace: " Project-X ", want: "simple_memories_project_x"},
+		{namespace: "team.a/b c", want: "simple_memories_team_a_b_c"},
+		{namespace: "x; DROP TABLE y", want: "simple_memories_x__drop_table_y"},
+		{namespace: "café", want: "simple_memories_caf_"},
+		{namespace: "fts", wantErr: `namespace "fts" must not end in _fts`},
+		{namespace: "notes_FTS", wantErr: `namespace "notes_FTS" must not end in _fts`},
+		{namespace: "my-fts", wantErr: "must not end in _fts"},
+		{namespace: "ftsdata", want: "simple_memories_ftsdata"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			got, err := tableName(tt.namespace)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("tableName(%q) = %q, %v, want error %q", tt.namespace, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("tableName(%q) = %q, %v, want %q", tt.namespace, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamespaceIsolation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+	open := func(namespace string) *SimpleMemoryServer {
+		t.Helper()
+		cfg := DefaultConfig()
+		cfg.Namespace = namespace
+		s, err := Open(path, cfg)
+		if err != nil {
+			t.Fatalf("open namespace %q: %v", namespace, err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	}
+	a, b, plain := open("team-a"), open("Team B"), open("")
+	mustAdd(t, a, map[string]any{"memory": "alpha deploy notes", "tags": "ops"}, map[string]any{"memory": "alpha roadmap"})
+	mustAdd(t, b, map[string]any{"memory": "beta deploy notes", "tags": "ops"})
+	mustAdd(t, plain, map[string]any{"memory": "default deploy notes"})
+
+	search := func(s *SimpleMemoryServer, query string) []int64 {
+		t.Helper()
+		out, isErr := callTool(t, s.SimpleMemorySearch, map[string]any{"query": query})
+		if isErr {
+			t.Fatalf("search: %s", out)
+		}
+		var memories []Memory
+		_ = json.Unmarshal([]byte(out), &memories)
+		// FTS orders by rank; only which memories match matters here.
+		ids := memoryIDs(memories)
+		slices.Sort(ids)
+		return ids
+	}
+	for _, tt := range []struct {
+		name       string
+		s          *SimpleMemoryServer
+		wantIDs    []int64
+		wantDeploy []int64
+		wantAlpha  []int64
+	}{
+		{"team-a", a, []int64{1, 2}, []int64{1}, []int64{1, 2}},
+		{"Team B", b, []int64{1}, []int64{1}, []int64{}},
+		{"default", plain, []int64{1}, []int64{1}, []int64{}},
+	} {
+		if ids := listIDs(t, tt.s); !equalIDs(ids, tt.wantIDs) {
+			t.Fatalf("%s lists %v, want %v", tt.name, ids, tt.wantIDs)
+		}
+		if ids := search(tt.s, "deploy"); !equalIDs(ids, tt.wantDeploy) {
+			t.Fatalf("%s finds deploy in %v, want %v", tt.name, ids, tt.wantDeploy)
+		}
+		if ids := search(tt.s, "alpha"); !equalIDs(ids, tt.wantAlpha) {
+			t.Fatalf("%s finds alpha in %v, want %v", tt.name, ids, tt.wantAlpha)
+		}
+	}
+
+	// Deleting in one namespace leaves the others alone, and a second server on the same
+	// namespace shares its memories.
+	if out, isErr := callTool(t, b.SimpleMemoryDelete, map[string]any{"query": "deploy"}); isErr || out != "Deleted 1 simple-memories." {
+		t.Fatalf("delete in Team B: got %q (error=%v)", out, isErr)
+	}
+	if ids := listIDs(t, a); !equalIDs(ids, []int64{1, 2}) {
+		t.Fatalf("team-a lists %v after deleting in Team B, want [1 2]", ids)
+	}
+	if ids := listIDs(t, open("TEAM_A")); !equalIDs(ids, []int64{1, 2}) {
+		t.Fatalf("TEAM_A lists %v, want the memories of team-a", ids)
+	}
+	var tables []string
+	rows, err := a.db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE 'simple_memories%' AND name NOT LIKE '%fts_%' ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	want := []string{"simple_memories", "simple_memories_team_a", "simple_memories_team_b"}
+	if a.fts {
+		want = []string{"simple_memories", "simple_memories_fts", "simple_memories_team_a", "simple_memories_team_a_fts", "simple_memories_team_b", "simple_memories_team_b_fts"}
+	}
+	if !slices.Equal(tables, want) {
+		t.Fatalf("tables %v, want %v", tables, want)
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	countHealth := func() *HealthStatus {
+		t.Helper()
+		out, isErr := callTool(t, s.SimpleMemoryCount, map[string]any{})
+		if isErr {
+			t.Fatalf("count: %s", out)
+		}
+		var counts struct {
+			Health *HealthStatus `json:"health"`
+		}
+		if err := json.Unmarshal([]byte(out), &counts); err != nil {
+			t.Fatalf("decode %q: %v", out, err)
+		}
+		return counts.Health
+	}
+	if h := countHealth(); h != nil {
+		t.Fatalf("health %+v before any probe, want none", h)
+	}
+	if h := s.checkHealth(context.Background()); !h.Healthy {
+		t.Fatalf("healthy probe failed: %s", h.Error)
+	}
+
+	// A read-only database still answers pings but fails the write test.
+	if _, err := s.db.Exec("PRAGMA query_only = ON"); err != nil {
+		t.Fatal(err)
+	}
+	s.checkHealth(context.Background())
+	h := countHealth()
+	if h == nil || h.Healthy || !strings.Contains(h.Error, "write test") {
+		t.Fatalf("health %+v, want failed write test", h)
+	}
+	failedSince := h.Since
+	s.checkHealth(context.Background())
+	if h := countHealth(); h.Healthy || h.Since != failedSince {
+		t.Fatalf("health %+v, want still failing since %s", h, failedSince)
+	}
+
+	if _, err := s.db.Exec("PRAGMA query_only = OFF"); err != nil {
+		t.Fatal(err)
+	}
+	if h := s.checkHealth(context.Background()); !h.Healthy || h.Error != "" {
+		t.Fatalf("health %+v after recovery, want healthy", h)
+	}
+}
+
+func TestSimpleMemoryDueReminders(t *testing.T) {
+	tests := []struct {
+		name    string
+		seed    []map[string]any
+		ack     []int
+		asOf    string
+		wantIDs []int64
+	}{
+		{
+			name:    "due at exactly as_of",
+			seed:    []map[string]any{{"memory": "standup", "remind_at": "2024-06-07T09:00:00Z"}},
+			asOf:    "2024-06-07T09:00:00Z",
+			wantIDs: []int64{1},
+		},
+		{
+			name: "a millisecond early",
+			seed: []map[string]any{{"memory": "standup", "remind_at": "2024-06-07T09:00:00.001Z"}},
+			asOf: "2024-06-07T09:00:00Z",
+		},
+		{
+			name: "oldest reminder first",
+			seed: []map[string]any{
+				{"memory": "later", "remind_at": "2024-06-07"},
+				{"memory": "no reminder"},
+				{"memory": "earlier", "remind_at": "2024-06-01"},
+			},
+			asOf:    "2024-06-07",
+			wantIDs: []int64{3, 1},
+		},
+		{
+			name: "acknowledged reminders are not due",
+			seed: []map[string]any{
+				{"memory": "done", "remind_at": "2024-06-01"},
+				{"memory": "pending", "remind_at": "2024-06-02"},
+			},
+			ack:     []int{1},
+			asOf:    "2024-06-07",
+			wantIDs: []int64{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, DefaultConfig())
+			mustAdd(t, s, tt.seed...)
+			for _, id := range tt.ack {
+				if out, isErr := callTool(t, s.SimpleMemoryAckReminder, map[string]any{"id": id}); isErr {
+					t.Fatalf("ack %d: %s", id, out)
+				}
+			}
+			out, isErr := callTool(t, s.SimpleMemoryDueReminders, map[string]any{"as_of": tt.asOf})
+			if isErr {
+				t.Fatalf("due reminders: %s", out)
+			}
+			var due []reminder
+			if err := json.Unmarshal([]byte(out), &due); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			ids := []int64{}
+			for _, r := range due {
+				ids = append(ids, r.ID)
+			}
+			if !equalIDs(ids, tt.wantIDs) {
+				t.Fatalf("due %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryAckReminder(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "standup", "remind_at": "2024-06-01"},
+		map[string]any{"memory": "no reminder"},
+	)
+	due := func() string {
+		t.Helper()
+		out, isErr := callTool(t, s.SimpleMemoryDueReminders, map[string]any{"as_of": "2024-06-07"})
+		if isErr {
+			t.Fatalf("due reminders: %s", out)
+		}
+		return out
+	}
+	if out, isErr := callTool(t, s.SimpleMemoryAckReminder, map[string]any{"id": 1}); isErr || out != "Reminder for memory 1 acknowledged." {
+		t.Fatalf("ack: got %q (error=%v)", out, isErr)
+	}
+	if out := due(); out != "[]" {
+		t.Fatalf("due after ack: %s, want []", out)
+	}
+	for _, id := range []int{2, 9} {
+		if out, isErr := callTool(t, s.SimpleMemoryAckReminder, map[string]any{"id": id}); !isErr || out != fmt.Sprintf("no reminder found for memory with id %d", id) {
+			t.Fatalf("ack %d: got %q (error=%v), want no reminder error", id, out, isErr)
+		}
+	}
+	// Changing the reminder re-arms it.
+	if out, isErr := callTool(t, s.SimpleMemoryUpdate, map[string]any{"id": 1, "remind_at": "2024-06-05"}); isErr {
+		t.Fatalf("update: %s", out)
+	}
+	if out := due(); !strings.Contains(out, `"remind_at":"2024-06-05T00:00:00.000Z"`) {
+		t.Fatalf("due after re-arming: %s", out)
+	}
+}
+
+func TestWatchReminders(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	soon := time.Now().Add(100 * time.Millisecond).UTC().Format(createdAtFormat)
+	mustAdd(t, s,
+		map[string]any{"memory": "overdue", "title": "Standup", "remind_at": "2020-01-01"},
+		map[string]any{"memory": "acknowledged", "remind_at": "2020-01-02"},
+		map[string]any{"memory": "soon", "remind_at": soon},
+		map[string]any{"memory": "far off", "remind_at": "2100-01-01"},
+	)
+	if out, isErr := callTool(t, s.SimpleMemoryAckReminder, map[string]any{"id": 2}); isErr {
+		t.Fatalf("ack: %s", out)
+	}
+
+	type notification struct {
+		method string
+		params map[string]any
+	}
+	sent := make(chan notification, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.WatchReminders(ctx, 5*time.Millisecond, func(method string, params map[string]any) {
+			sent <- notification{method, params}
+		})
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, want := range []map[string]any{
+		{"id": int64(1), "title": "Standup", "content": "overdue", "remind_at": "2020-01-01T00:00:00.000Z"},
+		{"id": int64(3), "title": "", "content": "soon", "remind_at": soon},
+	} {
+		select {
+		case n := <-sent:
+			if n.method != "notifications/simple_memory/reminder_due" || !maps.Equal(n.params, want) {
+				t.Fatalf("got %s %v, want reminder_due %v", n.method, n.params, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no notification for %v", want)
+		}
+	}
+	// Each reminder is sent once, however many polls follow.
+	select {
+	case n := <-sent:
+		t.Fatalf("unexpected notification %s %v", n.method, n.params)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := newTestServer(t, DefaultConfig())
+	mustAdd(t, src,
+		map[string]any{"memory": "User prefers chi", "title": "Router", "tags": "go, http", "status": "open"},
+		map[string]any{"memory": "  indented\n\tcode\n", "title": "Snippet"},
+		map[string]any{"memory": "İstanbul — ΟΔΟΣ \"quoted\" 🚀", "tags": "unicode", "status": "done"},
+	)
+	exported, isErr := callTool(t, src.SimpleMemoryExport, nil)
+	if isErr {
+		t.Fatalf("export: %s", exported)
+	}
+	var decoded []any
+	if err := json.Unmarshal([]byte(exported), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range []struct {
+		name     string
+		memories any
+	}{
+		{"export text", exported},
+		{"decoded array", decoded},
+	} {
+		t.Run(payload.name, func(t *testing.T) {
+			dst := newTestServer(t, DefaultConfig())
+			out, isErr := callTool(t, dst.SimpleMemoryImport, map[string]any{"memories": payload.memories})
+			if isErr || out != "Imported 3 simple-memories." {
+				t.Fatalf("import: got %q (error=%v)", out, isErr)
+			}
+			if out, _ := callTool(t, dst.SimpleMemoryExport, nil); out != exported {
+				t.Fatalf("round trip changed the memories:\n got %s\nwant %s", out, exported)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryImportRollback(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		bad  map[string]any
+		// payload, if set, is imported instead of two good entries followed by bad.
+		payload any
+		wantErr string
+	}{
+		{name: "empty content in replace mode", mode: "replace", bad: map[string]any{"content": "  "}, wantErr: "memory 2: content cannot be empty, nothing was imported"},
+		{name: "invalid created_at in replace mode", mode: "replace", bad: map[string]any{"content": "x", "created_at": "yesterday"}, wantErr: `memory 2: invalid created_at "yesterday", nothing was imported`},
+		{name: "oversized content in replace mode", mode: "replace", bad: map[string]any{"content": strings.Repeat("x", 33)}, wantErr: "memory 2: memory exceeds max size of 32 bytes, nothing was imported"},
+		{name: "empty content in append mode", mode: "append", bad: map[string]any{"content": ""}, wantErr: "memory 2: content cannot be empty"},
+		{name: "empty array in replace mode", mode: "replace", payload: []any{}, wantErr: "replace mode needs at least one memory to import, nothing was deleted"},
+		{name: "empty array string in replace mode", mode: "replace", payload: "[]", wantErr: "replace mode needs at least one memory to import, nothing was deleted"},
+		{name: "null in replace mode", mode: "replace", payload: "null", wantErr: "replace mode needs at least one memory to import, nothing was deleted"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.MaxContentBytes = 32
+			s := newTestServer(t, cfg)
+			mustAdd(t, s,
+				map[string]any{"memory": "existing one", "tags": "keep"},
+				map[string]any{"memory": "existing two", "status": "open"},
+			)
+			before, err := s.allMemories()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var payload any = []any{
+				map[string]any{"content": "new one", "created_at": "2024-06-07T12:00:00Z"},
+				map[string]any{"content": "new two"},
+				tt.bad,
+			}
+			if tt.payload != nil {
+				payload = tt.payload
+			}
+			out, isErr := callTool(t, s.SimpleMemoryImport, map[string]any{"memories": payload, "mode": tt.mode})
+			if !isErr || !strings.Contains(out, tt.wantErr) {
+				t.Fatalf("got %q (error=%v), want an error containing %q", out, isErr, tt.wantErr)
+			}
+			after, err := s.allMemories()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(after, before) {
+				t.Fatalf("failed import left %+v, want %+v", after, before)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryFixTimestamps(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	stored := []string{
+		"2024-06-01T10:00:00.000Z",
+		"2024-06-02 09:30:00",
+		"2024-06-03T12:00:00+02:00",
+		"2024-05-01",
+		"last tuesday",
+		"2024-06-04T08:00:00.000Z",
+		"2024-06-03T00:00:00.000Z",
+	}
+	for i, createdAt := range stored {
+		if _, err := s.db.Exec("INSERT INTO simple_memories (id, content, created_at) VALUES (?, ?, ?)", i+1, fmt.Sprintf("memory %d", i+1), createdAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createdAts := func() []string {
+		t.Helper()
+		rows, err := s.db.Query("SELECT CAST(created_at AS TEXT) FROM simple_memories ORDER BY id ASC")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var values []string
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				t.Fatal(err)
+			}
+			values = append(values, v)
+		}
+		return values
+	}
+	outOfOrder := []timestampAnomaly{
+		{ID: 5, CreatedAt: "last tuesday", Problem: "unparseable"},
+		{ID: 7, CreatedAt: "2024-06-03T00:00:00.000Z", Normalized: "2024-06-03T00:00:00.000Z", Problem: "out_of_order"},
+	}
+	found := append([]timestampAnomaly{
+		{ID: 2, CreatedAt: "2024-06-02 09:30:00", Normalized: "2024-06-02T09:30:00.000Z", Problem: "malformed"},
+		{ID: 3, CreatedAt: "2024-06-03T12:00:00+02:00", Normalized: "2024-06-03T10:00:00.000Z", Problem: "malformed"},
+		{ID: 4, CreatedAt: "2024-05-01", Normalized: "2024-05-01T00:00:00.000Z", Problem: "malformed"},
+		{ID: 4, CreatedAt: "2024-05-01", Normalized: "2024-05-01T00:00:00.000Z", Problem: "out_of_order"},
+	}, outOfOrder...)
+	fixed := []string{
+		"2024-06-01T10:00:00.000Z",
+		"2024-06-02T09:30:00.000Z",
+		"2024-06-03T10:00:00.000Z",
+		"2024-05-01T00:00:00.000Z",
+		"last tuesday",
+		"2024-06-04T08:00:00.000Z",
+		"2024-06-03T00:00:00.000Z",
+	}
+	steps := []struct {
+		name          string
+		fix           bool
+		wantFixed     int
+		wantAnomalies []timestampAnomaly
+		wantStored    []string
+	}{
+		{
+			name:          "report",
+			wantAnomalies: found,
+			wantStored:    stored,
+		},
+		{
+			name:          "fix",
+			fix:           true,
+			wantFixed:     3,
+			wantAnomalies: found,
+			wantStored:    fixed,
+		},
+		{
+			name: "report after fix",
+			wantAnomalies: append([]timestampAnomaly{
+				{ID: 4, CreatedAt: "2024-05-01T00:00:00.000Z", Normalized: "2024-05-01T00:00:00.000Z", Problem: "out_of_order"},
+			}, outOfOrder...),
+			wantStored: fixed,
+		},
+	}
+	// The steps run in order against the same database.
+	for _, step := range steps {
+		out, isErr := callTool(t, s.SimpleMemoryFixTimestamps, map[string]any{"fix": step.fix})
+		if isErr {
+			t.Fatalf("%s: %s", step.name, out)
+		}
+		var got struct {
+			Scanned   int                `json:"scanned"`
+			Fixed     int                `json:"fixed"`
+			Anomalies []timestampAnomaly `json:"anomalies"`
+		}
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Scanned != len(stored) || got.Fixed != step.wantFixed || !slices.Equal(got.Anomalies, step.wantAnomalies) {
+			t.Fatalf("%s: got %s, want %d scanned, %d fixed and anomalies %s", step.name, out, len(stored), step.wantFixed, mustJSON(t, step.wantAnomalies))
+		}
+		if values := createdAts(); !slices.Equal(values, step.wantStored) {
+			t.Fatalf("%s: stored %q, want %q", step.name, values, step.wantStored)
+		}
+	}
+}
+
+func TestMalformedCreatedAt(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.FileDir = dir
+	s := newTestServer(t, cfg)
+	mustAdd(t, s,
+		map[string]any{"memory": "go api notes"},
+		map[string]any{"memory": "go api sketch"},
+		map[string]any{"memory": "go api draft"},
+	)
+	stored := map[int64]string{2: "last tuesday", 3: "2024-06-02 09:30:00"}
+	for id, v := range stored {
+		if _, err := s.db.Exec("UPDATE "+s.table+" SET created_at = ? WHERE id = ?", v, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	decode := func(t *testing.T, out string) []Memory {
+		t.Helper()
+		var memories []Memory
+		if err := json.Unmarshal([]byte(out), &memories); err != nil {
+			t.Fatalf("decode %q: %v", out, err)
+		}
+		return memories
+	}
+	tests := []struct {
+		name string
+		run  func(t *testing.T) []Memory
+	}{
+		{"list", func(t *testing.T) []Memory {
+			out, _ := callTool(t, s.SimpleMemoryList, map[string]any{})
+			var page memoryPage
+			if err := json.Unmarshal([]byte(out), &page); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			return page.Memories
+		}},
+		{"search", func(t *testing.T) []Memory {
+			out, _ := callTool(t, s.SimpleMemorySearch, map[string]any{"query": "api"})
+			return decode(t, out)
+		}},
+		{"get", func(t *testing.T) []Memory {
+			out, _ := callTool(t, s.SimpleMemoryGet, map[string]any{"id": 2})
+			return decode(t, "["+out+"]")
+		}},
+		{"export", func(t *testing.T) []Memory {
+			out, _ := callTool(t, s.SimpleMemoryExport, map[string]any{})
+			return decode(t, out)
+		}},
+		// Runs last: the rows it archives are deleted.
+		{"archive_and_clear", func(t *testing.T) []Memory {
+			path := filepath.Join(dir, "archive.json")
+			if out, isErr := callTool(t, s.SimpleMemoryArchiveAndClear, map[string]any{"path": path, "confirm": true}); isErr {
+				t.Fatalf("archive: %s", out)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return decode(t, string(data))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[int64]string{}
+			for _, m := range tt.run(t) {
+				got[m.ID] = m.CreatedAt
+			}
+			if got[2] != stored[2] || (tt.name != "get" && (len(got) != 3 || got[3] != stored[3])) {
+				t.Fatalf("created_at by ID %v, want the stored %v", got, stored)
+			}
+		})
+	}
+}
+
+func TestWatchExports(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.WatchExports(ctx, dir, 5*time.Millisecond, 2)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	// nextExport waits for an export newer than prev and checks that no other follows it while
+	// the data is unchanged. It returns all exports.
+	nextExport := func(prev string) []string {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			paths, err := s.exportFiles(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) > 0 && paths[len(paths)-1] != prev {
+				time.Sleep(50 * time.Millisecond)
+				if again, _ := s.exportFiles(dir); !slices.Equal(again, paths) {
+					t.Fatalf("exports %v, want %v while unchanged", again, paths)
+				}
+				return paths
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("no export after %q", prev)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	paths := nextExport("")
+	mustAdd(t, s, map[string]any{"memory": "first"})
+	paths = nextExport(paths[len(paths)-1])
+	if len(paths) != 2 {
+		t.Fatalf("exports %v, want 2", paths)
+	}
+	mustAdd(t, s, map[string]any{"memory": "second"})
+	paths = nextExport(paths[1])
+	if len(paths) != 2 {
+		t.Fatalf("exports %v, want the newest 2 kept", paths)
+	}
+
+	data, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []Memory
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("decode %s: %v", paths[1], err)
+	}
+	if ids := memoryIDs(exported); !equalIDs(ids, []int64{1, 2}) {
+		t.Fatalf("newest export has ids %v, want [1 2]", ids)
+	}
+}
+
+func TestFileLoggerConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		// want is the expected rotation config, with Filename relative to the test directory
+		// unless absolute; nil means logging is disabled.
+		want    *lumberjack.Logger
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: &lumberjack.Logger{Filename: "/tmp/mcp-simple-memory-server.log", MaxSize: 10, MaxBackups: 2, MaxAge: 7},
+		},
+		{
+			name: "configured",
+			env: map[string]string{
+				"SIMPLE_MEMORY_LOG_PATH":        "logs/nested/server.log",
+				"SIMPLE_MEMORY_LOG_MAX_SIZE":    "1",
+				"SIMPLE_MEMORY_LOG_MAX_BACKUPS": "0",
+				"SIMPLE_MEMORY_LOG_MAX_AGE":     " 30 ",
+			},
+			want: &lumberjack.Logger{Filename: "logs/nested/server.log", MaxSize: 1, MaxBackups: 0, MaxAge: 30},
+		},
+		{name: "negative size", env: map[string]string{"SIMPLE_MEMORY_LOG_MAX_SIZE": "-1"}, wantErr: `invalid SIMPLE_MEMORY_LOG_MAX_SIZE "-1": must be a non-negative integer`},
+		{name: "non-numeric backups", env: map[string]string{"SIMPLE_MEMORY_LOG_MAX_BACKUPS": "two"}, wantErr: `invalid SIMPLE_MEMORY_LOG_MAX_BACKUPS "two"`},
+		{
+			name: "disabled",
+			env:  map[string]string{"DISABLE_SIMPLE_MEMORY_LOGGING": "TRUE", "SIMPLE_MEMORY_LOG_PATH": "logs/server.log", "SIMPLE_MEMORY_LOG_MAX_SIZE": "-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Chdir(dir)
+			for _, name := range []string{"DISABLE_SIMPLE_MEMORY_LOGGING", "SIMPLE_MEMORY_LOG_PATH", "SIMPLE_MEMORY_LOG_MAX_SIZE", "SIMPLE_MEMORY_LOG_MAX_BACKUPS", "SIMPLE_MEMORY_LOG_MAX_AGE"} {
+				t.Setenv(name, tt.env[name])
+			}
+			cfg, err := ConfigFromEnv()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == nil {
+				if cfg.Logger != nil {
+					t.Fatal("logger configured although logging is disabled")
+				}
+				s := newTestServer(t, cfg)
+				mustAdd(t, s, map[string]any{"memory": "not logged"})
+				if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+					t.Fatalf("disabled logging wrote %v (%v)", entries, err)
+				}
+				return
+			}
+			lj, ok := cfg.Logger.Writer().(*lumberjack.Logger)
+			if !ok {
+				t.Fatalf("logger writes to %T, want a rotating file", cfg.Logger.Writer())
+			}
+			if lj.Filename != tt.want.Filename || lj.MaxSize != tt.want.MaxSize || lj.MaxBackups != tt.want.MaxBackups || lj.MaxAge != tt.want.MaxAge {
+				t.Fatalf("got %s size=%d backups=%d age=%d, want %s size=%d backups=%d age=%d",
+					lj.Filename, lj.MaxSize, lj.MaxBackups, lj.MaxAge,
+					tt.want.Filename, tt.want.MaxSize, tt.want.MaxBackups, tt.want.MaxAge)
+			}
+			if filepath.IsAbs(lj.Filename) {
+				return
+			}
+			defer lj.Close()
+			s := newTestServer(t, cfg)
+			mustAdd(t, s, map[string]any{"memory": "logged"})
+			data, err := os.ReadFile(lj.Filename)
+			if err != nil || !strings.Contains(string(data), "[INFO] Added simple-memory") {
+				t.Fatalf("log %q (%v), want the add logged", data, err)
+			}
+			// Writing past MaxSize megabytes rotates the file.
+			line := strings.Repeat("x", 1023)
+			for range 1500 {
+				cfg.Logger.Print(line)
+			}
+			backups, err := filepath.Glob(filepath.Join(filepath.Dir(lj.Filename), "server-*.log"))
+			if err != nil || len(backups) == 0 {
+				t.Fatalf("no rotated backups next to %s (%v)", lj.Filename, err)
+			}
+			if info, err := os.Stat(lj.Filename); err != nil || info.Size() > int64(tt.want.MaxSize)<<20 {
+				t.Fatalf("current log %v (%v), want at most %d MB", info, err, tt.want.MaxSize)
+			}
+		})
+	}
+}
+
+func TestToolDescriptionOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "descriptions.yaml")
+	if err := os.WriteFile(path, []byte("simple_memory_add: From the file.\nsimple_memory_get: From the file.\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SIMPLE_MEMORY_TOOL_DESCRIPTIONS", path)
+	t.Setenv("SIMPLE_MEMORY_TOOL_DESCRIPTION_GET", "From the env.")
+	descriptions, err := toolDescriptionsFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.ToolDescriptions = descriptions
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	newTestServer(t, cfg).RegisterTools(mcpServer)
+	resp := mcpServer.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	out, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Result mcp.ListToolsResult `json:"result"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		t.Fatalf("decode %s: %v", out, err)
+	}
+	got := map[string]string{}
+	for _, tool := range list.Result.Tools {
+		got[tool.Name] = tool.Description
+	}
+	for name, want := range map[string]string{
+		"simple_memory_add": "From the file.",
+		"simple_memory_get": "From the env.",
+	} {
+		if got[name] != want {
+			t.Fatalf("%s description %q, want %q", name, got[name], want)
+		}
+	}
+	if d := got["simple_memory_list"]; d == "" || strings.HasPrefix(d, "From the") {
+		t.Fatalf("simple_memory_list description %q, want the built-in one", d)
+	}
+}
+
+func TestSimpleMemoryImportMarkdown(t *testing.T) {
+	type note struct{ Title, Tags, Status, Content string }
+	tests := []struct {
+		name    string
+		cfg     func(*Config)
+		files   map[string]string
+		split   string
+		want    []note
+		wantErr string
+	}{
+		{
+			name:  "full front matter",
+			files: map[string]string{"a.md": "---\ntitle: Router\ntags: [go, http]\nstatus: open\n---\nUse chi.\n"},
+			want:  []note{{"Router", "go, http", "open", "Use chi."}},
+		},
+		{
+			name:  "partial front matter",
+			files: map[string]string{"router-notes.md": "---\ntags: go,  http\n---\n\nUse chi.\n"},
+			want:  []note{{"router-notes", "go, http", "", "Use chi."}},
+		},
+		{
+			name:  "no front matter",
+			files: map[string]string{"plain.md": "Use chi.\n"},
+			want:  []note{{"plain", "", "", "Use chi."}},
+		},
+		{
+			name:  "unterminated front matter is content",
+			files: map[string]string{"open.md": "---\ntitle: x\nbody"},
+			want:  []note{{"open", "", "", "---\ntitle: x\nbody"}},
+		},
+		{
+			name:  "sections",
+			files: map[string]string{"n.md": "---\ntags: go\n---\nintro\n# One\nfirst\n## Two\nsecond\n# Empty\n"},
+			split: "section",
+			want:  []note{{"n", "go", "", "intro"}, {"One", "go", "", "first"}, {"Two", "go", "", "second"}},
+		},
+		{
+			name:    "invalid front matter",
+			files:   map[string]string{"a.md": "ok\n", "b.md": "---\ntags: {bad\n---\nbody\n"},
+			wantErr: "failed to parse",
+		},
+		{
+			name:    "oversized note",
+			cfg:     func(c *Config) { c.MaxContentBytes = 10 },
+			files:   map[string]string{"a.md": "short\n", "b.md": "this note is far too long\n"},
+			wantErr: "memory exceeds max size of 10 bytes",
+		},
+		{
+			name:    "note below minimum",
+			cfg:     func(c *Config) { c.MinContentChars = 5 },
+			files:   map[string]string{"a.md": "long enough\n", "b.md": "# Title\nhi\n"},
+			split:   "section",
+			wantErr: "memory is too short",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			dir := t.TempDir()
+			cfg.FileDir = dir
+			s := newTestServer(t, cfg)
+			for name, text := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			args := map[string]any{"path": dir}
+			if tt.split != "" {
+				args["split"] = tt.split
+			}
+			out, isErr := callTool(t, s.SimpleMemoryImportMarkdown, args)
+			if tt.wantErr != "" {
+				if !isErr || !strings.Contains(out, tt.wantErr) || !strings.Contains(out, "nothing was imported") {
+					t.Fatalf("got %q (error=%v), want an error containing %q", out, isErr, tt.wantErr)
+				}
+			} else if isErr {
+				t.Fatalf("import: %s", out)
+			}
+			memories, err := s.allMemories()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []note{}
+			for _, m := range memories {
+				got = append(got, note{m.Title, m.Tags, m.Status, m.Content})
+			}
+			if tt.want == nil {
+				tt.want = []note{}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("stored %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "files")
+	outside := filepath.Join(root, "outside")
+	for _, d := range []string{filepath.Join(dir, "notes.d"), outside} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		fileDir string
+		exts    []string
+		path    string
+		dirOK   bool
+		want    string
+		wantErr string
+	}{
+		{name: "json", path: filepath.Join(dir, "backup.json"), want: "backup.json"},
+		{name: "relative to the file dir", path: "sub/backup.json", want: "sub/backup.json"},
+		{name: "extension case ignored", path: filepath.Join(dir, "NOTES.MD"), want: "NOTES.MD"},
+		{name: "sqlite", path: filepath.Join(dir, "split.db"), want: "split.db"},
+		{name: "disallowed extension", path: filepath.Join(dir, "backup.sh"), wantErr: `extension ".sh" is not allowed`},
+		{name: "no extension", path: filepath.Join(dir, "backup"), wantErr: `extension "" is not allowed`},
+		{name: "directory where allowed", path: filepath.Join(dir, "notes.d"), dirOK: true, want: "notes.d"},
+		{name: "directory where not allowed", path: filepath.Join(dir, "notes.d"), wantErr: `extension ".d" is not allowed`},
+		{name: "empty allowlist uses the defaults", exts: []string{}, path: filepath.Join(dir, "backup.sh"), wantErr: `extension ".sh" is not allowed`},
+		{name: "absolute path outside", path: "/etc/cron.d/x.json", wantErr: "must be inside"},
+		{name: "parent segment", path: "../backup.json", wantErr: "must be inside"},
+		{name: "inner parent segment", path: dir + "/x/../../backup.json", wantErr: "must be inside"},
+		{name: "parent segment that stays inside", path: "x/../backup.json", want: "backup.json"},
+		{name: "symlink out of the file dir", path: "link/backup.json", wantErr: "must be inside"},
+		{name: "sibling with a common prefix", path: dir + "-other/backup.json", wantErr: "must be inside"},
+		{name: "dots in a name are fine", path: filepath.Join(dir, "v1..2.json"), want: "v1..2.json"},
+		{name: "file access not configured", fileDir: "-", path: filepath.Join(dir, "backup.json"), wantErr: "file access is not configured"},
+		{name: "empty", path: " ", wantErr: "path cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.FileDir = dir
+			if tt.fileDir == "-" {
+				cfg.FileDir = ""
+			}
+			if tt.exts != nil {
+				cfg.PathExtensions = tt.exts
+			}
+			s := newTestServer(t, cfg)
+			got, err := s.toolPath(tt.path, tt.dirOK)
+			if (tt.wantErr == "" && err != nil) || (tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr))) {
+				t.Fatalf("toolPath(%q): got %v, want %q", tt.path, err, tt.wantErr)
+			}
+			if tt.want != "" {
+				if want := filepath.Join(s.fileDir, filepath.FromSlash(tt.want)); got != want {
+					t.Fatalf("toolPath(%q) = %q, want %q", tt.path, got, want)
+				}
+			}
+		})
+	}
+
+	cfg := DefaultConfig()
+	cfg.FileDir = dir
+	s := newTestServer(t, cfg)
+	mustAdd(t, s, map[string]any{"memory": "keep me"})
+	out, isErr := callTool(t, s.SimpleMemoryArchiveAndClear, map[string]any{"path": filepath.Join(dir, "archive.txt"), "confirm": true})
+	if !isErr || !strings.Contains(out, "is not allowed") {
+		t.Fatalf("archive to .txt: got %q (error=%v), want extension error", out, isErr)
+	}
+	if out, _ := callTool(t, s.SimpleMemoryCount, nil); !strings.Contains(out, `"total":1`) {
+		t.Fatalf("rejected archive cleared memories: %s", out)
+	}
+}
+
+func TestSimpleMemoryExportDB(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.FileDir = t.TempDir()
+	s := newTestServer(t, cfg)
+	mustAdd(t, s,
+		map[string]any{"memory": "chi router", "tags": "proj-a", "remind_at": "2030-01-01"},
+		map[string]any{"memory": "other project", "tags": "proj-b"},
+		map[string]any{"memory": "pgx driver", "tags": "proj-a", "status": "open"},
+		map[string]any{"memory": "old thing", "tags": "proj-a, archive", "status": "done"},
+	)
+	path := filepath.Join(cfg.FileDir, "proj-a.db")
+	args := map[string]any{"path": path, "tags": "proj-a", "exclude_tags": "archive"}
+	out, isErr := callTool(t, s.SimpleMemoryExportDB, args)
+	if isErr {
+		t.Fatalf("export db: %s", out)
+	}
+	if want := fmt.Sprintf(`{"path":%q,"count":2}`, path); out != want {
+		t.Fatalf("got %s, want %s", out, want)
+	}
+	if out, isErr := callTool(t, s.SimpleMemoryExportDB, args); !isErr {
+		t.Fatalf("second export over %s: got %q, want error", path, out)
+	}
+
+	db, err := sql.Open(DriverName, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported, err := New(db, DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exported.Close()
+	if ids := listIDs(t, exported); !equalIDs(ids, []int64{1, 3}) {
+		t.Fatalf("exported ids %v, want [1 3]", ids)
+	}
+	for _, id := range []int64{1, 3} {
+		var want, got string
+		query := "SELECT title || '|' || tags || '|' || COALESCE(status, '') || '|' || content || '|' || created_at || '|' || COALESCE(remind_at, '') FROM simple_memories WHERE id = ?"
+		if err := s.db.QueryRow(query, id).Scan(&want); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.QueryRow(query, id).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("exported memory %d is %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestSimpleMemoryArchiveAndClear(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup prepares dir and returns the path to archive to.
+		setup   func(t *testing.T, dir string) string
+		wantErr string
+	}{
+		{
+			name:  "archived",
+			setup: func(t *testing.T, dir string) string { return filepath.Join(dir, "archive.json") },
+		},
+		{
+			name:    "missing directory",
+			setup:   func(t *testing.T, dir string) string { return filepath.Join(dir, "missing", "archive.json") },
+			wantErr: "failed to export simple-memories, nothing was cleared",
+		},
+		{
+			name: "existing file",
+			setup: func(t *testing.T, dir string) string {
+				path := filepath.Join(dir, "archive.json")
+				if err := os.WriteFile(path, []byte("[]"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return path
+			},
+			wantErr: "already exists",
+		},
+		{
+			name: "unwritable directory",
+			setup: func(t *testing.T, dir string) string {
+				if os.Geteuid() == 0 {
+					t.Skip("root can write to read-only directories")
+				}
+				sub := filepath.Join(dir, "ro")
+				if err := os.Mkdir(sub, 0o500); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { _ = os.Chmod(sub, 0o755) })
+				return filepath.Join(sub, "archive.json")
+			},
+			wantErr: "failed to export simple-memories, nothing was cleared",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := tt.setup(t, dir)
+			cfg := DefaultConfig()
+			cfg.FileDir = dir
+			s := newTestServer(t, cfg)
+			mustAdd(t, s,
+				map[string]any{"memory": "pgx driver", "title": "db", "tags": "go", "remind_at": "2020-01-01"},
+				map[string]any{"memory": "chi router", "remind_at": "2030-01-01T09:00:00Z"},
+				map[string]any{"memory": "no reminder"},
+			)
+			if out, isErr := callTool(t, s.SimpleMemoryAckReminder, map[string]any{"id": 1}); isErr {
+				t.Fatalf("ack: %s", out)
+			}
+			before, err := s.allMemories()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, isErr := callTool(t, s.SimpleMemoryArchiveAndClear, map[string]any{"path": path, "confirm": true})
+			after, err := s.allMemories()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if !isErr || !strings.Contains(out, tt.wantErr) {
+					t.Fatalf("got %q (error=%v), want an error containing %q", out, isErr, tt.wantErr)
+				}
+				if !slices.Equal(after, before) {
+					t.Fatalf("failed archive changed the table: %+v, want %+v", after, before)
+				}
+				return
+			}
+			if isErr {
+				t.Fatalf("archive: %s", out)
+			}
+			if len(after) != 0 {
+				t.Fatalf("table holds %+v after archive, want it empty", after)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var archived []archivedMemory
+			if err := json.Unmarshal(data, &archived); err != nil {
+				t.Fatalf("decode archive %s: %v", data, err)
+			}
+			if len(archived) != len(before) {
+				t.Fatalf("archived %d memories, want %d", len(archived), len(before))
+			}
+			for i, m := range archived {
+				if m.Memory != before[i] {
+					t.Fatalf("archived %+v, want %+v", m.Memory, before[i])
+				}
+			}
+			str := func(p *string) string {
+				if p == nil {
+					return "<nil>"
+				}
+				return *p
+			}
+			for i, want := range []struct{ remindAt, acked string }{
+				{"2020-01-01T00:00:00.000Z", "acked"},
+				{"2030-01-01T09:00:00.000Z", "<nil>"},
+				{"<nil>", "<nil>"},
+			} {
+				acked := str(archived[i].ReminderAckedAt)
+				if acked != "<nil>" {
+					acked = "acked"
+				}
+				if got := str(archived[i].RemindAt); got != want.remindAt || acked != want.acked {
+					t.Fatalf("memory %d: remind_at %s, reminder_acked_at %s, want %s and %s", archived[i].ID, got, acked, want.remindAt, want.acked)
+				}
+			}
+			if !strings.Contains(string(data), `"reminder_acked_at": null`) {
+				t.Fatalf("archive does not record unset reminder fields as null: %s", data)
+			}
+		})
+	}
+}
+
+func TestSimpleMemorySimilar(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"title": "Chi router", "memory": "User prefers Chi router over Gin", "tags": "go,http"},
+		map[string]any{"title": "Gin router", "memory": "Gin router benchmarks", "tags": "go"},
+		map[string]any{"title": "Chi", "memory": "chi router: user prefers it over gin", "tags": "HTTP,Go"},
+		map[string]any{"title": "Lunch", "memory": "Pizza on Friday", "tags": "food"},
+		map[string]any{"title": "Server", "memory": "Plain net/http server", "tags": "http"},
+	)
+	type scored struct {
+		ID    int64   `json:"id"`
+		Score float64 `json:"score"`
+	}
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    []scored
+		wantErr string
+	}{
+		{
+			// 3 shares 6 of 7 words and, case-insensitively, both tags; 2 shares 2 of 7 words
+			// and 1 of 2 tags; 5 shares no words but the http tag; 4 shares nothing.
+			name: "ranked by word and tag overlap",
+			args: map[string]any{"id": 1},
+			want: []scored{{3, 0.9}, {2, 0.35}, {5, 0.15}, {4, 0}},
+		},
+		{
+			name: "top_k truncates",
+			args: map[string]any{"id": 1, "top_k": 2},
+			want: []scored{{3, 0.9}, {2, 0.35}},
+		},
+		{
+			name: "top_k above the count returns all",
+			args: map[string]any{"id": 1, "top_k": 10},
+			want: []scored{{3, 0.9}, {2, 0.35}, {5, 0.15}, {4, 0}},
+		},
+		{
+			name: "ties keep ID order",
+			args: map[string]any{"id": 4},
+			want: []scored{{1, 0}, {2, 0}, {3, 0}, {5, 0}},
+		},
+		{
+			name:    "unknown reference",
+			args:    map[string]any{"id": 99},
+			wantErr: "no memory found with id 99",
+		},
+		{
+			name:    "missing reference",
+			args:    map[string]any{},
+			wantErr: "invalid params",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemorySimilar, tt.args)
+			if tt.wantErr != "" {
+				if !isErr || !strings.Contains(out, tt.wantErr) {
+					t.Fatalf("got %q (error=%v), want error %q", out, isErr, tt.wantErr)
+				}
+				return
+			}
+			if isErr {
+				t.Fatalf("similar: %s", out)
+			}
+			var got []scored
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("ranked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryNearDuplicates(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "The user prefers the chi router for HTTP services in Go."},
+		map[string]any{"memory": "User prefers the chi router for HTTP services written in Go!"},
+		map[string]any{"memory": "For Go HTTP services the user likes chi as the router."},
+		map[string]any{"memory": "Deploy with docker compose on the staging box."},
+	)
+	tests := []struct {
+		name string
+		args map[string]any
+		want []duplicateCluster
+	}{
+		{
+			name: "defaults catch the light rewording",
+			args: map[string]any{},
+			want: []duplicateCluster{{IDs: []int64{1, 2}, Pairs: []duplicatePair{{1, 2, 0.667}}}},
+		},
+		{
+			name: "word sets catch the reordered rewording",
+			args: map[string]any{"shingle_size": 1, "threshold": 0.6},
+			want: []duplicateCluster{{IDs: []int64{1, 2, 3}, Pairs: []duplicatePair{{1, 2, 0.909}, {1, 3, 0.667}, {2, 3, 0.615}}}},
+		},
+		{
+			name: "transitive pairs below the threshold are still reported",
+			args: map[string]any{"shingle_size": 1, "threshold": 0.65},
+			want: []duplicateCluster{{IDs: []int64{1, 2, 3}, Pairs: []duplicatePair{{1, 2, 0.909}, {1, 3, 0.667}, {2, 3, 0.615}}}},
+		},
+		{
+			name: "strict threshold",
+			args: map[string]any{"shingle_size": 1, "threshold": 0.95},
+			want: []duplicateCluster{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemoryNearDuplicates, tt.args)
+			if isErr {
+				t.Fatalf("near duplicates: %s", out)
+			}
+			var result struct {
+				Clusters []duplicateCluster `json:"clusters"`
+			}
+			if err := json.Unmarshal([]byte(out), &result); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if got, _ := json.Marshal(result.Clusters); string(got) != mustJSON(t, tt.want) {
+				t.Fatalf("clusters %s, want %s", got, mustJSON(t, tt.want))
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryScanPII(t *testing.T) {
+	seed := []map[string]any{
+		{"memory": "Contact jane.doe@example.com or call (555) 123-4567 about the invoice."},
+		{"memory": "Test card 4111 1111 1111 1111 works in staging.", "title": "Payments"},
+		{"memory": "Build 2024-01-15 passed; order 1234567890123 shipped.", "tags": "ci"},
+		{"memory": "Employee number EMP-00042 onboarded.", "title": "HR"},
+		{"memory": "Deployed at 1718000000; ticket 5551234567 and order 555-1234567 closed.", "tags": "ops"},
+		{"memory": "Call +1 5551234567 or 555.123.4567 after hours.", "tags": "ops"},
+	}
+	tests := []struct {
+		name     string
+		patterns map[string]string
+		args     map[string]any
+		want     []piiFinding
+		wantErr  bool
+	}{
+		{
+			name: "built-in detectors",
+			args: map[string]any{},
+			want: []piiFinding{
+				{ID: 1, Categories: []string{"email", "phone"}, Matches: []piiMatch{
+					{Category: "email", Field: "content", Masked: "ja****************om"},
+					{Category: "phone", Field: "content", Masked: "(5**********67"},
+				}},
+				{ID: 2, Title: "Payments", Categories: []string{"credit_card"}, Matches: []piiMatch{
+					{Category: "credit_card", Field: "content", Masked: "41***************11"},
+				}},
+				{ID: 6, Categories: []string{"phone"}, Matches: []piiMatch{
+					{Category: "phone", Field: "content", Masked: "+1*********67"},
+					{Category: "phone", Field: "content", Masked: "55********67"},
+				}},
+			},
+		},
+		{
+			name: "one category",
+			args: map[string]any{"categories": []any{"Email"}},
+			want: []piiFinding{
+				{ID: 1, Categories: []string{"email"}, Matches: []piiMatch{{Category: "email", Field: "content", Masked: "ja****************om"}}},
+			},
+		},
+		{
+			name: "filtered",
+			args: map[string]any{"tags": "ci"},
+			want: []piiFinding{},
+		},
+		{
+			name:     "custom and disabled detectors",
+			patterns: map[string]string{"employee_id": `EMP-\d+`, "phone": "", "credit_card": ""},
+			args:     map[string]any{},
+			want: []piiFinding{
+				{ID: 1, Categories: []string{"email"}, Matches: []piiMatch{{Category: "email", Field: "content", Masked: "ja****************om"}}},
+				{ID: 4, Title: "HR", Categories: []string{"employee_id"}, Matches: []piiMatch{{Category: "employee_id", Field: "content", Masked: "EM*****42"}}},
+			},
+		},
+		{
+			name:    "unknown category",
+			args:    map[string]any{"categories": []any{"ssn"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.PIIPatterns = tt.patterns
+			s := newTestServer(t, cfg)
+			mustAdd(t, s, seed...)
+			out, isErr := callTool(t, s.SimpleMemoryScanPII, tt.args)
+			if isErr != tt.wantErr {
+				t.Fatalf("scan: error=%v, want %v: %s", isErr, tt.wantErr, out)
+			}
+			if tt.wantErr {
+				return
+			}
+			var result struct {
+				Scanned  int          `json:"scanned"`
+				Findings []piiFinding `json:"findings"`
+			}
+			if err := json.Unmarshal([]byte(out), &result); err != nil {
+				t.Fatalf("decode %q: %v", out, err)
+			}
+			if got, _ := json.Marshal(result.Findings); string(got) != mustJSON(t, tt.want) {
+				t.Fatalf("findings %s, want %s", got, mustJSON(t, tt.want))
+			}
+			if strings.Contains(out, "jane.doe") || strings.Contains(out, "4111 1111") {
+				t.Fatalf("scan output leaks a matched value: %s", out)
+			}
+		})
+	}
+
+	db, err := sql.Open(DriverName, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cfg := DefaultConfig()
+	cfg.PIIPatterns = map[string]string{"broken": "("}
+	if _, err := New(db, cfg); err == nil || !strings.Contains(err.Error(), "invalid PII pattern for broken") {
+		t.Fatalf("New with invalid pattern: got %v, want invalid PII pattern error", err)
+	}
+}
+
+func TestMissingOptionalColumns(t *testing.T) {
+	open := func(t *testing.T, schema string) *sql.DB {
+		t.Helper()
+		db, err := sql.Open(DriverName, ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		// A newer binary left a table without status or reminders; compatibility mode
+		// must not migrate it.
+		for _, stmt := range []string{schema, fmt.Sprintf("PRAGMA user_version = %d", schemaVersion+1)} {
+			if _, err := db.Exec(stmt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return db
+	}
+	db := open(t, `CREATE TABLE simple_memories (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT, tags TEXT, content TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')))`)
+	if _, err := db.Exec(`INSERT INTO simple_memories (title, tags, content) VALUES ('Router', 'go,http', 'User prefers chi'), ('', 'db', 'Use pgx')`); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.AllowNewerSchema = true
+	s, err := New(db, cfg)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	calls := []struct {
+		name    string
+		handler server.ToolHandlerFunc
+		args    map[string]any
+		want    string
+	}{
+		{"get", s.SimpleMemoryGet, map[string]any{"id": 1}, `"status":""`},
+		{"list", s.SimpleMemoryList, map[string]any{"tags": "db"}, `"content":"Use pgx"`},
+		{"search", s.SimpleMemorySearch, map[string]any{"query": "chi"}, `"title":"Router"`},
+		{"count", s.SimpleMemoryCount, map[string]any{}, `"total":2`},
+		{"status filter", s.SimpleMemoryList, map[string]any{"status": "open"}, `"total":0`},
+		{"export", s.SimpleMemoryExport, nil, `"content":"Use pgx"`},
+	}
+	for _, call := range calls {
+		t.Run(call.name, func(t *testing.T) {
+			out, isErr := callTool(t, call.handler, call.args)
+			if isErr || !strings.Contains(out, call.want) {
+				t.Fatalf("got %q (error=%v), want it to contain %q", out, isErr, call.want)
+			}
+		})
+	}
+
+	db = open(t, `CREATE TABLE simple_memories (id INTEGER PRIMARY KEY, title TEXT, content TEXT NOT NULL)`)
+	defer db.Close()
+	if _, err := New(db, cfg); err == nil || !strings.Contains(err.Error(), "no created_at column") {
+		t.Fatalf("New without created_at: got %v, want missing column error", err)
+	}
+}
+
+func TestNewerSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   bool
+		wantErr string
+	}{
+		{name: "refused by default", wantErr: fmt.Sprintf("database schema version %d is newer than this binary supports (%d)", schemaVersion+1, schemaVersion)},
+		{name: "compatibility mode", allow: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := sql.Open(DriverName, ":memory:")
+			if err != nil {
+				t.Fatal(err)
+			}
+			db.SetMaxOpenConns(1)
+			defer db.Close()
+			for _, stmt := range []string{
+				`CREATE TABLE simple_memories (id INTEGER PRIMARY KEY, title TEXT, tags TEXT, content TEXT NOT NULL, created_at DATETIME NOT NULL)`,
+				fmt.Sprintf("PRAGMA user_version = %d", schemaVersion+1),
+			} {
+				if _, err := db.Exec(stmt); err != nil {
+					t.Fatal(err)
+				}
+			}
+			schema := func() string {
+				t.Helper()
+				var version int
+				if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+					t.Fatal(err)
+				}
+				var tables []string
+				rows, err := db.Query("SELECT name || ': ' || sql FROM sqlite_master ORDER BY name")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer rows.Close()
+				for rows.Next() {
+					var table string
+					if err := rows.Scan(&table); err != nil {
+						t.Fatal(err)
+					}
+					tables = append(tables, table)
+				}
+				return fmt.Sprintf("version %d, %v", version, tables)
+			}
+			before := schema()
+
+			cfg := DefaultConfig()
+			cfg.AllowNewerSchema = tt.allow
+			s, err := New(db, cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New: got %v, want %q", err, tt.wantErr)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("New: %v", err)
+				}
+				if h := s.checkHealth(context.Background()); !h.Healthy {
+					t.Fatalf("health %+v, want healthy", h)
+				}
+			}
+			if after := schema(); after != before {
+				t.Fatalf("schema changed from %s to %s", before, after)
+			}
+		})
+	}
+}
+
+func TestMojibakeProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "ascii", text: "plain text"},
+		{name: "accents", text: "café naïve Ωμέγα 🚀"},
+		{name: "double-encoded two-byte", text: "cafÃ©", want: []string{"possible double-encoded UTF-8"}},
+		{name: "double-encoded via cp1252", text: "itâ€™s", want: []string{"possible double-encoded UTF-8"}},
+		{name: "double-encoded four-byte", text: "launch ðŸš€", want: []string{"possible double-encoded UTF-8"}},
+		{name: "lead byte at the end", text: "grade Ã"},
+		{name: "lead byte before ascii", text: "Ã and Â are letters"},
+		{name: "sharp s before an ellipsis", text: "heiß…"},
+		{name: "sharp s before a quote", text: "„daß“ und „weiß”"},
+		{name: "umlaut before a low quote", text: "Ä‚"},
+		{name: "umlaut before a dash", text: "Öl–Preis"},
+		{name: "double-encoded via cp1252 after umlauts", text: "Ä‚ itâ€™s", want: []string{"possible double-encoded UTF-8"}},
+		{name: "replacement character", text: "it\uFFFDs", want: []string{"replacement character (U+FFFD)"}},
+		{name: "invalid UTF-8", text: "bad \xff byte", want: []string{"invalid UTF-8"}},
+		{name: "invalid and double-encoded", text: "\xfe cafÃ©", want: []string{"invalid UTF-8", "possible double-encoded UTF-8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mojibakeProblems(tt.text); !slices.Equal(got, tt.want) {
+				t.Fatalf("mojibakeProblems(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryFindMojibake(t *testing.T) {
+	tests := []struct {
+		name string
+		seed []map[string]any
+		want string
+	}{
+		{
+			name: "clean",
+			seed: []map[string]any{{"memory": "café", "title": "Menu", "tags": "food"}},
+			want: "No encoding problems found.",
+		},
+		{
+			name: "problems in each field",
+			seed: []map[string]any{
+				{"memory": "fine", "title": "Clean"},
+				{"memory": "cafÃ©", "title": "Menu"},
+				{"memory": "fine", "title": "RÃ©sumÃ©", "tags": "caf\uFFFD"},
+				{"memory": "bad \xff byte"},
+			},
+			want: `[{"id":2,"title":"Menu","problems":["content: possible double-encoded UTF-8"]},` +
+				`{"id":3,"title":"RÃ©sumÃ©","problems":["title: possible double-encoded UTF-8","tags: replacement character (U+FFFD)"]},` +
+				`{"id":4,"title":"","problems":["content: invalid UTF-8"]}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, DefaultConfig())
+			mustAdd(t, s, tt.seed...)
+			out, isErr := callTool(t, s.SimpleMemoryFindMojibake, nil)
+			if isErr || out != tt.want {
+				t.Fatalf("got %s (error=%v), want %s", out, isErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimpleMemoryTrends(t *testing.T) {
+	seed := []any{
+		map[string]any{"content": "a", "tags": "go, http", "status": "open", "created_at": "2024-06-02T23:59:59Z"},
+		map[string]any{"content": "b", "tags": "go,go", "status": "open", "created_at": "2024-06-03T00:00:00Z"},
+		map[string]any{"content": "c", "tags": " http ", "created_at": "2024-06-09T12:00:00Z"},
+		map[string]any{"content": "d", "tags": "go", "status": "done", "created_at": "2024-07-01T08:00:00+02:00"},
+		map[string]any{"content": "e", "created_at": "2024-07-01T10:00:00Z"},
+	}
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    string
+		wantErr string
+	}{
+		{
+			name: "tags by day",
+			args: map[string]any{},
+			want: `[{"key":"go","bucket":"2024-06-02","count":1},{"key":"go","bucket":"2024-06-03","count":1},{"key":"go","bucket":"2024-07-01","count":1},` +
+				`{"key":"http","bucket":"2024-06-02","count":1},{"key":"http","bucket":"2024-06-09","count":1}]`,
+		},
+		{
+			name: "tags by week",
+			args: map[string]any{"bucket": "week"},
+			want: `[{"key":"go","bucket":"2024-W22","count":1},{"key":"go","bucket":"2024-W23","count":1},{"key":"go","bucket":"2024-W27","count":1},` +
+				`{"key":"http","bucket":"2024-W22","count":1},{"key":"http","bucket":"2024-W23","count":1}]`,
+		},
+		{
+			name: "tags by month",
+			args: map[string]any{"group_by": "tag", "bucket": "Month"},
+			want: `[{"key":"go","bucket":"2024-06","count":2},{"key":"go","bucket":"2024-07","count":1},{"key":"http","bucket":"2024-06","count":2}]`,
+		},
+		{
+			name: "status by month",
+			args: map[string]any{"group_by": "status", "bucket": "month"},
+			want: `[{"key":"(none)","bucket":"2024-06","count":1},{"key":"(none)","bucket":"2024-07","count":1},{"key":"done","bucket":"2024-07","count":1},` +
+				`{"key":"open","bucket":"2024-06","count":2}]`,
+		},
+		{name: "invalid bucket", args: map[string]any{"bucket": "year"}, wantErr: `invalid bucket "year": must be day, week, or month`},
+		{name: "invalid group_by", args: map[string]any{"group_by": "title"}, wantErr: `invalid group_by "title": must be tag or status`},
+	}
+	s := newTestServer(t, DefaultConfig())
+	if out, isErr := callTool(t, s.SimpleMemoryImport, map[string]any{"memories": seed}); isErr {
+		t.Fatalf("import: %s", out)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, isErr := callTool(t, s.SimpleMemoryTrends, tt.args)
+			if tt.wantErr != "" {
+				if !isErr || out != tt.wantErr {
+					t.Fatalf("got %q (error=%v), want error %q", out, isErr, tt.wantErr)
+				}
+				return
+			}
+			if isErr || out != tt.want {
+				t.Fatalf("got %s (error=%v)\nwant %s", out, isErr, tt.want)
+			}
+		})
+	}
+
+	t.Run("weeks across year boundaries", func(t *testing.T) {
+		// Each date is labelled with its ISO week, as time.ISOWeek computes it.
+		dates := []string{
+			"2020-12-31T12:00:00Z", "2021-01-03T23:59:59Z", // Thursday and Sunday of 2020-W53
+			"2024-12-30T00:00:00Z",                                                 // Monday of 2025-W01
+			"2025-12-29T00:00:00Z", "2026-01-01T09:00:00Z", "2026-01-04T23:59:59Z", // 2026-W01
+			"2026-01-05T00:00:00Z", // 2026-W02
+		}
+		want := map[string]int64{}
+		var memories []any
+		for _, d := range dates {
+			ts, err := time.Parse(time.RFC3339, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			year, week := ts.ISOWeek()
+			want[fmt.Sprintf("%d-W%02d", year, week)]++
+			memories = append(memories, map[string]any{"content": d, "tags": "x", "created_at": d})
+		}
+		s := newTestServer(t, DefaultConfig())
+		if out, isErr := callTool(t, s.SimpleMemoryImport, map[string]any{"memories": memories}); isErr {
+			t.Fatalf("import: %s", out)
+		}
+		out, isErr := callTool(t, s.SimpleMemoryTrends, map[string]any{"bucket": "week"})
+		if isErr {
+			t.Fatalf("trends: %s", out)
+		}
+		var points []struct {
+			Bucket string `json:"bucket"`
+			Count  int64  `json:"count"`
+		}
+		if err := json.Unmarshal([]byte(out), &points); err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]int64{}
+		for _, p := range points {
+			got[p.Bucket] = p.Count
+		}
+		if !maps.Equal(got, want) || len(points) != 4 {
+			t.Fatalf("weeks %s, want %v", out, want)
+		}
+	})
+}
+
+func TestSimpleMemoryStorageStats(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	for i := range 40 {
+		mustAdd(t, s, map[string]any{"memory": fmt.Sprintf("%d %s", i, strings.Repeat("padding ", 500))})
+	}
+	stats := func(args map[string]any) (result struct {
+		storageStats
+		VacuumRecommended bool          `json:"vacuum_recommended"`
+		AfterVacuum       *storageStats `json:"after_vacuum"`
+	}) {
+		t.Helper()
+		out, isErr := callTool(t, s.SimpleMemoryStorageStats, args)
+		if isErr {
+			t.Fatalf("storage stats: %s", out)
+		}
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatalf("decode %q: %v", out, err)
+		}
+		return result
+	}
+
+	if got := stats(map[string]any{"vacuum": true}); got.VacuumRecommended || got.FreePages != 0 || got.AfterVacuum != nil {
+		t.Fatalf("fresh database: got %+v, want no free pages and no recommendation", got)
+	}
+	if _, err := s.db.Exec("DELETE FROM " + s.table + " WHERE id > 4"); err != nil {
+		t.Fatal(err)
+	}
+	got := stats(nil)
+	if !got.VacuumRecommended || got.Fragmentation < 0.5 || got.AfterVacuum != nil {
+		t.Fatalf("after deleting most rows: got %+v, want a recommendation and no vacuum", got)
+	}
+	if high := stats(map[string]any{"threshold": 0.99}); high.VacuumRecommended {
+		t.Fatalf("threshold above fragmentation %v still recommended vacuum", high.Fragmentation)
+	}
+	got = stats(map[string]any{"vacuum": true})
+	if got.AfterVacuum == nil || got.AfterVacuum.FreePages != 0 || got.AfterVacuum.PageCount >= got.PageCount {
+		t.Fatalf("vacuum: got %+v, after %+v, want the free pages reclaimed", got, got.AfterVacuum)
+	}
+	if again := stats(nil); again.VacuumRecommended {
+		t.Fatalf("after vacuum: got %+v, want no recommendation", again)
+	}
+	if out, _ := callTool(t, s.SimpleMemoryCount, nil); !strings.Contains(out, `"total":4`) {
+		t.Fatalf("vacuum lost memories: %s", out)
+	}
+}
@@ -0,0 +1,769 @@
+// Package memory implements the simple-memory MCP tools on top of a SQLite database.
+package memory
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/mattn/go-sqlite3"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	trueString = "true"
+	// defaultTable is the memory table used when no namespace is configured.
+	defaultTable = "simple_memories"
+	// defaultListLimit is the page size used by simple_memory_list when no limit is given.
+	defaultListLimit = 50
+	// defaultMaxContentBytes caps the size of a memory's content unless SIMPLE_MEMORY_MAX_CONTENT_BYTES says otherwise.
+	defaultMaxContentBytes = 64 * 1024
+	// schemaVersion is the simple_memories schema version this binary creates and understands.
+	// Bump it whenever a migration is added.
+	schemaVersion = 2
+)
+
+// Memory represents a single memory entry in the database. CreatedAt holds the stored text,
+// normally in createdAtFormat, so a malformed timestamp is returned and exported as stored.
+type Memory struct {
+	ID        int64  `json:"id"`
+	Title     string `json:"title"`
+	Tags      string `json:"tags"`
+	Status    string `json:"status"`
+	Content   string `json:"content"`
+	CreatedAt string `json:"created_at"`
+}
+
+// SearchWeights holds the per-field weights used to rank search results.
+type SearchWeights struct {
+	Title   float64
+	Tags    float64
+	Status  float64
+	Content float64
+}
+
+// loadSearchWeights reads the SIMPLE_MEMORY_WEIGHT_* env vars, falling back to w.
+func loadSearchWeights(w SearchWeights) (SearchWeights, error) {
+	fields := []struct {
+		env string
+		dst *float64
+	}{
+		{"SIMPLE_MEMORY_WEIGHT_TITLE", &w.Title},
+		{"SIMPLE_MEMORY_WEIGHT_TAGS", &w.Tags},
+		{"SIMPLE_MEMORY_WEIGHT_STATUS", &w.Status},
+		{"SIMPLE_MEMORY_WEIGHT_CONTENT", &w.Content},
+	}
+	for _, f := range fields {
+		v := strings.TrimSpace(os.Getenv(f.env))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) || n < 0 {
+			return w, fmt.Errorf("invalid %s %q: must be a non-negative number", f.env, v)
+		}
+		*f.dst = n
+	}
+	return w, nil
+}
+
+// envInt reads a non-negative integer env var, returning def when it is unset.
+func envInt(name string, def int) (int, error) {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", name, v)
+	}
+	return n, nil
+}
+
+// newFileLogger creates the rolling file logger configured by the SIMPLE_MEMORY_LOG_* env vars.
+func newFileLogger() (*log.Logger, error) {
+	path := strings.TrimSpace(os.Getenv("SIMPLE_MEMORY_LOG_PATH"))
+	if path == "" {
+		path = "/tmp/mcp-simple-memory-server.log"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory for %s: %w", path, err)
+	}
+	maxSize, err := envInt("SIMPLE_MEMORY_LOG_MAX_SIZE", 10)
+	if err != nil {
+		return nil, err
+	}
+	maxBackups, err := envInt("SIMPLE_MEMORY_LOG_MAX_BACKUPS", 2)
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := envInt("SIMPLE_MEMORY_LOG_MAX_AGE", 7)
+	if err != nil {
+		return nil, err
+	}
+	lj := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAge,
+		Compress:   false,
+	}
+	return log.New(lj, "", log.LstdFlags|log.Lmicroseconds), nil
+}
+
+// SimpleMemoryServer manages SQLite3 DB and logging for memory operations.
+type SimpleMemoryServer struct {
+	db             *sql.DB
+	logger         *log.Logger
+	disableLogging bool
+	weights        SearchWeights
+	templateDir    string
+	// table is the memory table in use (see tableName) and ftsTable its full-text index.
+	table    string
+	ftsTable string
+	fts      bool
+	// view is what reads select from: table itself, or a subquery standing NULL in for the
+	// optional columns table lacks (see memoryView).
+	view string
+	// compat is set when the schema is newer than schemaVersion; the server then never
+	// changes it.
+	compat      bool
+	contextTags []string
+	// unicodeFold makes LIKE matching case-insensitive for all scripts rather than only ASCII.
+	unicodeFold bool
+	// maxContentBytes is the largest content, in bytes, add and update accept; 0 means unlimited.
+	maxContentBytes int
+	// minContentChars is the fewest characters (runes) add and update accept; 0 means no minimum.
+	minContentChars int
+	// preserveWhitespace holds the fields (title, tags, status, content) stored untrimmed.
+	preserveWhitespace map[string]bool
+	// structuredResults wraps the answers of list-like tools in {"results":[...],"count":n}.
+	structuredResults bool
+	// deleteConfirmTTL, when positive, makes deletes two-step: the first call returns a token
+	// valid this long that a second call must pass back (see confirmedDelete).
+	deleteConfirmTTL time.Duration
+	deleteTokensMu   sync.Mutex
+	deleteTokens     map[string]deleteToken
+	// listOrder is the listOrders scheme simple_memory_list sorts by.
+	listOrder string
+	// extractTitle moves a heading-like first line of untitled content into the title on add.
+	extractTitle bool
+	// toolDescriptions replaces the built-in descriptions of the tools it names.
+	toolDescriptions map[string]string
+	// fileDir is the absolute directory tool path parameters must resolve inside; empty
+	// disables the file tools. pathExtensions are the file extensions those paths may have.
+	fileDir        string
+	pathExtensions []string
+	// piiDetectors are the compiled simple_memory_scan_pii patterns by category.
+	piiDetectors map[string]*regexp.Regexp
+	// health is the latest result of WatchHealth, or nil when the monitor is not running.
+	healthMu sync.Mutex
+	health   *HealthStatus
+}
+
+// tableName returns the memory table for a namespace: simple_memories, or
+// simple_memories_<namespace> with the namespace lowercased and every character other
+// than a letter, digit or underscore replaced by an underscore so it is a safe identifier.
+func tableName(namespace string) (string, error) {
+	namespace = strings.TrimSpace(namespace)
+	if namespace == "" {
+		return defaultTable, nil
+	}
+	ns := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(namespace))
+	// <table>_fts is the full-text index of <table>, so such names would collide.
+	if ns == "fts" || strings.HasSuffix(ns, "_fts") {
+		return "", fmt.Errorf("namespace %q must not end in _fts", namespace)
+	}
+	return defaultTable + "_" + ns, nil
+}
+
+// DriverName is the database/sql driver to open memory databases with: go-sqlite3 with the
+// simple_memory_fold(text) SQL function registered on every connection.
+const DriverName = "sqlite3_simple_memory"
+
+func init() {
+	sql.Register(DriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("simple_memory_fold", foldCase, true)
+		},
+	})
+}
+
+// foldCase lowercases s rune by rune with Unicode simple case mapping, so that for example
+// "İ" and "I" both become "i", and maps final sigma to σ so "ΟΔΟΣ" and "οδος" fold alike.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == 'ς' {
+			return 'σ'
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// Config holds the settings of a SimpleMemoryServer. DefaultConfig returns the defaults and
+// ConfigFromEnv reads them from the SIMPLE_MEMORY_* environment variables.
+type Config struct {
+	// Logger receives operation logs; nil disables logging.
+	Logger  *log.Logger
+	Weights SearchWeights
+	// TemplateDir holds the <name>.tmpl content templates; empty disables templates.
+	TemplateDir string
+	// Namespace selects the table simple_memories_<namespace> (see tableName); empty uses simple_memories.
+	Namespace string
+	// ContextTags are appended to the tags of every new memory.
+	ContextTags []string
+	// UnicodeFold makes LIKE matching case-insensitive for all scripts rather than only ASCII.
+	UnicodeFold bool
+	// MaxContentBytes is the largest content, in bytes, add and update accept; 0 means unlimited.
+	MaxContentBytes int
+	// MinContentChars is the fewest characters (runes) add and update accept; 0 means no minimum.
+	MinContentChars int
+	// PreserveWhitespace lists the fields (title, tags, status, content) that add, update, and
+	// import store as given instead of trimming surrounding whitespace.
+	PreserveWhitespace []string
+	// DeleteConfirmTTL, when positive, requires deletes to be confirmed with a token that is
+	// valid this long; 0 deletes in one call.
+	DeleteConfirmTTL time.Duration
+	// ListOrder names the order simple_memory_list returns memories in: "oldest" (by ID, the
+	// default when empty) or "recent" (newest created_at first).
+	ListOrder string
+	// ExtractTitle moves a heading-like first line of untitled content into the title on add
+	// (see splitHeading).
+	ExtractTitle bool
+	// ToolDescriptions replaces the built-in descriptions of the tools it names.
+	ToolDescriptions map[string]string
+	// FileDir is the directory the paths given to import and export tools must be inside;
+	// relative paths are resolved against it. Empty disables those tools.
+	FileDir string
+	// PathExtensions are the lower-case file extensions, with their dot, that the paths given
+	// to import and export tools may have. Empty means defaultPathExtensions.
+	PathExtensions []string
+	// PIIPatterns adds or replaces simple_memory_scan_pii detectors by category; an empty
+	// pattern disables that built-in detector (see defaultPIIPatterns).
+	PIIPatterns map[string]string
+	// StructuredResults makes list-like tools answer with {"results":[...],"count":n}, plus
+	// tool-specific totals, for every result, empty or not, instead of their legacy shapes.
+	StructuredResults bool
+	// AllowNewerSchema opens databases migrated by a newer version in compatibility mode
+	// instead of refusing them.
+	AllowNewerSchema bool
+}
+
+// DefaultConfig returns the configuration used when no environment variables are set,
+// with logging disabled.
+func DefaultConfig() Config {
+	return Config{
+		Weights:         SearchWeights{Title: 3, Tags: 2, Status: 1, Content: 1},
+		MaxContentBytes: defaultMaxContentBytes,
+		PathExtensions:  defaultPathExtensions,
+	}
+}
+
+// ConfigFromEnv builds a Config from the environment, creating the rolling log file unless
+// DISABLE_SIMPLE_MEMORY_LOGGING is true.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	var err error
+	if cfg.Weights, err = loadSearchWeights(cfg.Weights); err != nil {
+		return cfg, err
+	}
+	// Only create the rolling log file when logging is enabled
+	if strings.ToLower(os.Getenv("DISABLE_SIMPLE_MEMORY_LOGGING")) != trueString {
+		if cfg.Logger, err = newFileLogger(); err != nil {
+			return cfg, err
+		}
+	}
+	cfg.TemplateDir = strings.TrimSpace(os.Getenv("SIMPLE_MEMORY_TEMPLATE_DIR"))
+	cfg.Namespace = os.Getenv("SIMPLE_MEMORY_NAMESPACE")
+	if _, err := tableName(cfg.Namespace); err != nil {
+		return cfg, fmt.Errorf("invalid SIMPLE_MEMORY_NAMESPACE: %w", err)
+	}
+	cfg.ContextTags = splitTags(os.Getenv("SIMPLE_MEMORY_CONTEXT_TAGS"))
+	cfg.UnicodeFold = strings.ToLower(os.Getenv("SIMPLE_MEMORY_UNICODE_SEARCH")) == trueString
+	if cfg.MaxContentBytes, err = envInt("SIMPLE_MEMORY_MAX_CONTENT_BYTES", cfg.MaxContentBytes); err != nil {
+		return cfg, err
+	}
+	if cfg.MinContentChars, err = envInt("SIMPLE_MEMORY_MIN_CONTENT_CHARS", cfg.MinContentChars); err != nil {
+		return cfg, err
+	}
+	if cfg.PreserveWhitespace, err = preservedFields(os.Getenv("SIMPLE_MEMORY_TRIM_FIELDS")); err != nil {
+		return cfg, err
+	}
+	switch format := strings.ToLower(strings.TrimSpace(os.Getenv("SIMPLE_MEMORY_RESULT_FORMAT"))); format {
+	case "", "legacy":
+	case "structured":
+		cfg.StructuredResults = true
+	default:
+		return cfg, fmt.Errorf("invalid SIMPLE_MEMORY_RESULT_FORMAT %q: must be legacy or structured", format)
+	}
+	if v := strings.TrimSpace(os.Getenv("SIMPLE_MEMORY_DELETE_CONFIRM_TTL")); v != "" {
+		if cfg.DeleteConfirmTTL, err = time.ParseDuration(v); err != nil || cfg.DeleteConfirmTTL <= 0 {
+			return cfg, fmt.Errorf("invalid SIMPLE_MEMORY_DELETE_CONFIRM_TTL %q: must be a positive duration such as 5m", v)
+		}
+	}
+	cfg.ListOrder = strings.ToLower(strings.TrimSpace(os.Getenv("SIMPLE_MEMORY_DEFAULT_ORDER")))
+	cfg.ExtractTitle = strings.ToLower(os.Getenv("SIMPLE_MEMORY_EXTRACT_TITLE")) == trueString
+	if cfg.ToolDescriptions, err = toolDescriptionsFromEnv(); err != nil {
+		return cfg, err
+	}
+	cfg.PIIPatterns = piiPatternsFromEnv()
+	cfg.FileDir = strings.TrimSpace(os.Getenv("SIMPLE_MEMORY_FILE_DIR"))
+	if cfg.PathExtensions, err = pathExtensions(os.Getenv("SIMPLE_MEMORY_PATH_EXTENSIONS"), cfg.PathExtensions); err != nil {
+		return cfg, err
+	}
+	cfg.AllowNewerSchema = strings.ToLower(os.Getenv("SIMPLE_MEMORY_ALLOW_NEWER_SCHEMA")) == trueString
+	return cfg, nil
+}
+
+// toolDescriptionEnvPrefix prefixes env vars overriding one tool's description, e.g.
+// SIMPLE_MEMORY_TOOL_DESCRIPTION_ADD for simple_memory_add.
+const toolDescriptionEnvPrefix = "SIMPLE_MEMORY_TOOL_DESCRIPTION_"
+
+// toolDescriptionsFromEnv reads tool description overrides from the YAML or JSON file of tool
+// names to descriptions named by SIMPLE_MEMORY_TOOL_DESCRIPTIONS, then from the per-tool env
+// vars, which take precedence.
+func toolDescriptionsFromEnv() (map[string]string, error) {
+	descriptions := map[string]string{}
+	if path := strings.TrimSpace(os.Getenv("SIMPLE_MEMORY_TOOL_DESCRIPTIONS")); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SIMPLE_MEMORY_TOOL_DESCRIPTIONS: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &descriptions); err != nil {
+			return nil, fmt.Errorf("invalid SIMPLE_MEMORY_TOOL_DESCRIPTIONS file %s: %w", path, err)
+		}
+	}
+	for _, kv := range os.Environ() {
+		name, description, _ := strings.Cut(kv, "=")
+		if suffix, ok := strings.CutPrefix(name, toolDescriptionEnvPrefix); ok && suffix != "" {
+			descriptions["simple_memory_"+strings.ToLower(suffix)] = description
+		}
+	}
+	for name, description := range descriptions {
+		if strings.TrimSpace(description) == "" {
+			return nil, fmt.Errorf("invalid description override for %s: must not be empty", name)
+		}
+	}
+	return descriptions, nil
+}
+
+// piiPatternEnvPrefix prefixes env vars setting one simple_memory_scan_pii detector, e.g.
+// SIMPLE_MEMORY_PII_PATTERN_IBAN for the "iban" category.
+const piiPatternEnvPrefix = "SIMPLE_MEMORY_PII_PATTERN_"
+
+// piiPatternsFromEnv reads the PII detector patterns set by SIMPLE_MEMORY_PII_PATTERN_<CATEGORY>.
+func piiPatternsFromEnv() map[string]string {
+	patterns := map[string]string{}
+	for _, kv := range os.Environ() {
+		name, pattern, _ := strings.Cut(kv, "=")
+		if suffix, ok := strings.CutPrefix(name, piiPatternEnvPrefix); ok && suffix != "" {
+			patterns[strings.ToLower(suffix)] = strings.TrimSpace(pattern)
+		}
+	}
+	return patterns
+}
+
+// defaultPathExtensions are the file types the import and export tools read and write.
+var defaultPathExtensions = []string{".json", ".ndjson", ".csv", ".md", ".db", ".sqlite", ".sqlite3"}
+
+// pathExtensions parses SIMPLE_MEMORY_PATH_EXTENSIONS, a comma-separated list of extensions,
+// falling back to def when unset. Leading dots are optional.
+func pathExtensions(value string, def []string) ([]string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return def, nil
+	}
+	var exts []string
+	for _, ext := range splitTags(value) {
+		ext = "." + strings.TrimPrefix(ext, ".")
+		if ext == "." || strings.ContainsAny(ext[1:], `./\`) {
+			return nil, fmt.Errorf("invalid SIMPLE_MEMORY_PATH_EXTENSIONS entry %q: must be an extension such as .json", ext)
+		}
+		exts = append(exts, ext)
+	}
+	return exts, nil
+}
+
+// trimmableFields are the memory fields whose surrounding whitespace is trimmed by default.
+var trimmableFields = []string{"title", "tags", "status", "content"}
+
+// preservedFields parses SIMPLE_MEMORY_TRIM_FIELDS, a comma-separated list of the fields to
+// trim or "none", into the fields to store untrimmed. Unset means trim every field.
+func preservedFields(value string) ([]string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil, nil
+	}
+	trim := map[string]bool{}
+	if value != "none" {
+		for _, field := range splitTags(value) {
+			if !slices.Contains(trimmableFields, field) {
+				return nil, fmt.Errorf("invalid SIMPLE_MEMORY_TRIM_FIELDS field %q: must be one of %s, or none", field, strings.Join(trimmableFields, ", "))
+			}
+			trim[field] = true
+		}
+	}
+	var preserved []string
+	for _, field := range trimmableFields {
+		if !trim[field] {
+			preserved = append(preserved, field)
+		}
+	}
+	return preserved, nil
+}
+
+// Open opens the SQLite database at dbPath in WAL mode and returns a server using it.
+func Open(dbPath string, cfg Config) (*SimpleMemoryServer, error) {
+	db, err := sql.Open(DriverName, dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite3 db: %w", err)
+	}
+	// Set WAL mode for better concurrency
+	_, _ = db.Exec("PRAGMA journal_mode=WAL;")
+	s, err := New(db, cfg)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New returns a server storing memories in db, creating or migrating its table first. db
+// should be opened with DriverName so the SQL functions the server relies on exist. The
+// server takes ownership of db and closes it in Close.
+func New(db *sql.DB, cfg Config) (*SimpleMemoryServer, error) {
+	table, err := tableName(cfg.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	listOrder := cfg.ListOrder
+	if listOrder == "" {
+		listOrder = "oldest"
+	}
+	if _, ok := listOrders[listOrder]; !ok {
+		return nil, fmt.Errorf("invalid default order %q: must be oldest or recent", cfg.ListOrder)
+	}
+	logger := cfg.Logger
+	disable := logger == nil
+	if disable {
+		logger = log.New(io.Discard, "", 0)
+	}
+
+	// Refuse to touch a schema migrated by a newer binary unless compatibility mode is on
+	var dbVersion int
+	if err := db.QueryRow("PRAGMA user_version;").Scan(&dbVersion); err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	compat := dbVersion > schemaVersion
+	if compat && !cfg.AllowNewerSchema {
+		return nil, fmt.Errorf(
+			"database schema version %d is newer than this binary supports (%d); upgrade the server or set SIMPLE_MEMORY_ALLOW_NEWER_SCHEMA=true to run in compatibility mode",
+			dbVersion, schemaVersion,
+		)
+	}
+
+	fts := false
+	if compat {
+		// Leave the newer schema untouched; only use the full-text index if it already works.
+		if !disable {
+			logger.Printf("[WARN] Database schema version %d is newer than supported version %d; running in compatibility mode without migrations", dbVersion, schemaVersion)
+		}
+		_, err := db.Exec("SELECT rowid FROM " + table + "_fts LIMIT 0")
+		fts = err == nil
+	} else {
+		if err := migrateSchema(db, table, dbVersion); err != nil {
+			return nil, err
+		}
+		// Full-text index; fall back to LIKE search when SQLite is built without FTS5
+		fts = true
+		if err := setupFTS(db, table); err != nil {
+			fts = false
+			if !disable {
+				logger.Printf("[WARN] FTS5 unavailable, falling back to LIKE search: %v", err)
+			}
+			if err := dropFTSTriggers(db, table); err != nil {
+				return nil, fmt.Errorf("failed to remove full-text triggers: %w", err)
+			}
+		}
+	}
+
+	preserve := map[string]bool{}
+	for _, field := range cfg.PreserveWhitespace {
+		preserve[field] = true
+	}
+	detectors, err := compilePIIPatterns(cfg.PIIPatterns)
+	if err != nil {
+		return nil, err
+	}
+	exts := cfg.PathExtensions
+	if len(exts) == 0 {
+		exts = defaultPathExtensions
+	}
+	fileDir := cfg.FileDir
+	if fileDir != "" {
+		if fileDir, err = resolvePath(fileDir); err != nil {
+			return nil, fmt.Errorf("invalid file directory %q: %w", cfg.FileDir, err)
+		}
+	}
+	view, missing, err := memoryView(db, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 && !disable {
+		logger.Printf("[WARN] Table %s has no %s column(s); reading them as empty", table, strings.Join(missing, ", "))
+	}
+
+	return &SimpleMemoryServer{
+		db:                 db,
+		logger:             logger,
+		disableLogging:     disable,
+		weights:            cfg.Weights,
+		templateDir:        cfg.TemplateDir,
+		table:              table,
+		ftsTable:           table + "_fts",
+		view:               view,
+		compat:             compat,
+		fts:                fts,
+		contextTags:        cfg.ContextTags,
+		unicodeFold:        cfg.UnicodeFold,
+		maxContentBytes:    cfg.MaxContentBytes,
+		minContentChars:    cfg.MinContentChars,
+		preserveWhitespace: preserve,
+		deleteConfirmTTL:   cfg.DeleteConfirmTTL,
+		deleteTokens:       map[string]deleteToken{},
+		listOrder:          listOrder,
+		extractTitle:       cfg.ExtractTitle,
+		toolDescriptions:   cfg.ToolDescriptions,
+		fileDir:            fileDir,
+		pathExtensions:     exts,
+		piiDetectors:       detectors,
+		structuredResults:  cfg.StructuredResults,
+	}, nil
+}
+
+// memoryColumns are the memory table columns reads rely on, in table order. The
+// requiredColumns among them cannot be stood in for.
+var (
+	memoryColumns   = []string{"id", "title", "tags", "status", "content", "created_at", "remind_at", "reminder_acked_at"}
+	requiredColumns = []string{"id", "content", "created_at"}
+)
+
+// tableColumns returns the names of the columns table has, per PRAGMA table_info.
+func tableColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query("PRAGMA table_info(" + table + ");")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check columns: %w", err)
+	}
+	// Close before returning rather than leaving it to the caller: a lingering read would pin
+	// a stale schema on its connection after any DDL that follows.
+	defer rows.Close()
+	present := map[string]bool{}
+	for rows.Next() {
+		var cid int
+		var name, ctype string
+		var notnull, pk int
+		var dfltValue sql.NullString
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dfltValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to check columns: %w", err)
+		}
+		present[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check columns: %w", err)
+	}
+	return present, nil
+}
+
+// memoryView returns the source reads should select from so that every memoryColumns name
+// resolves against the schema actually present: table itself when it has them all, else a
+// subquery reading the missing optional ones as NULL, along with the names it stood in for.
+// A schema left by an older or newer binary may lack columns; only requiredColumns must exist.
+func memoryView(db *sql.DB, table string) (string, []string, error) {
+	present, err := tableColumns(db, table)
+	if err != nil {
+		return "", nil, err
+	}
+	var missing []string
+	selects := make([]string, len(memoryColumns))
+	for i, col := range memoryColumns {
+		selects[i] = col
+		if present[col] {
+			continue
+		}
+		if slices.Contains(requiredColumns, col) {
+			return "", nil, fmt.Errorf("table %s has no %s column", table, col)
+		}
+		missing = append(missing, col)
+		selects[i] = "NULL AS " + col
+	}
+	if len(missing) == 0 {
+		return table, nil, nil
+	}
+	return "(SELECT " + strings.Join(selects, ", ") + " FROM " + table + ")", missing, nil
+}
+
+// migrateSchema creates the memory table, adds columns missing from older databases, and
+// records the schema version.
+func migrateSchema(db *sql.DB, table string, dbVersion int) error {
+	// Create schema if not exists
+	schema := `
+	CREATE TABLE IF NOT EXISTS ` + table + ` (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT,
+		tags TEXT,
+		status TEXT,
+		content TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now')),
+		remind_at TEXT,
+		reminder_acked_at TEXT
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	// Ensure new columns exist (for migrations)
+	columns := map[string]string{
+		"title":             "title TEXT",
+		"tags":              "tags TEXT",
+		"status":            "status TEXT",
+		"remind_at":         "remind_at TEXT",
+		"reminder_acked_at": "reminder_acked_at TEXT",
+	}
+	present, err := tableColumns(db, table)
+	if err != nil {
+		return err
+	}
+	for col, def := range columns {
+		if !present[col] {
+			_, _ = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + def + ";")
+		}
+	}
+	if dbVersion < schemaVersion {
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d;", schemaVersion)); err != nil {
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close checkpoints the WAL into the main database file, truncating the -wal file, and
+// closes the DB. It should be called once the server has stopped handling requests.
+func (s *SimpleMemoryServer) Close() error {
+	_, cpErr := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE);")
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close sqlite3 db: %w", err)
+	}
+	if cpErr != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", cpErr)
+	}
+	if !s.disableLogging {
+		s.logger.Printf("[INFO] Closed simple-memory DB")
+	}
+	return nil
+}
+
+// ftsTriggers returns, by name, the triggers that keep table's full-text index in sync with it.
+func ftsTriggers(table string) map[string]string {
+	fts := table + "_fts"
+	return map[string]string{
+		fts + "_ai": `
+	CREATE TRIGGER ` + fts + `_ai AFTER INSERT ON ` + table + ` BEGIN
+		INSERT INTO ` + fts + `(rowid, title, tags, status, content)
+		VALUES (new.id, new.title, new.tags, new.status, new.content);
+	END;`,
+		fts + "_ad": `
+	CREATE TRIGGER ` + fts + `_ad AFTER DELETE ON ` + table + ` BEGIN
+		INSERT INTO ` + fts + `(` + fts + `, rowid, title, tags, status, content)
+		VALUES ('delete', old.id, old.title, old.tags, old.status, old.content);
+	END;`,
+		fts + "_au": `
+	CREATE TRIGGER ` + fts + `_au AFTER UPDATE ON ` + table + ` BEGIN
+		INSERT INTO ` + fts + `(` + fts + `, rowid, title, tags, status, content)
+		VALUES ('delete', old.id, old.title, old.tags, old.status, old.content);
+		INSERT INTO ` + fts + `(rowid, title, tags, status, content)
+		VALUES (new.id, new.title, new.tags, new.status, new.content);
+	END;`,
+	}
+}
+
+// setupFTS creates the FTS5 index <table>_fts over table and its sync triggers. If any
+// trigger was missing (new DB, or a previous run without FTS5 dropped them) the index is
+// rebuilt, since rows may have changed while it was not being maintained.
+func setupFTS(db *sql.DB, table string) error {
+	fts := table + "_fts"
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS ` + fts + ` USING fts5(
+		title, tags, status, content,
+		content='` + table + `', content_rowid='id'
+	);`); err != nil {
+		return err
+	}
+	// IF NOT EXISTS skips the module lookup for an existing table, so probe it explicitly.
+	if _, err := tx.Exec("SELECT rowid FROM " + fts + " LIMIT 0"); err != nil {
+		return err
+	}
+	rebuild := false
+	for name, stmt := range ftsTriggers(table) {
+		var n int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?", name).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+		rebuild = true
+	}
+	if rebuild {
+		if _, err := tx.Exec("INSERT INTO " + fts + "(" + fts + ") VALUES ('rebuild');"); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// dropFTSTriggers removes the FTS sync triggers so writes keep working on SQLite builds
+// without FTS5, where the triggers would fail with "no such module".
+func dropFTSTriggers(db *sql.DB, table string) error {
+	for name := range ftsTriggers(table) {
+		if _, err := db.Exec("DROP TRIGGER IF EXISTS " + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ftsMatchExpr turns free text into an FTS5 query that requires every term, each matched as
+// a quoted token prefix so punctuation in the input cannot be parsed as FTS syntax.
+func ftsMatchExpr(query string) string {
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,346 @@
+package memory
+
+import (
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RegisterTools adds every simple-memory tool to mcpServer, backed by s. Configured description
+// overrides replace the built-in descriptions of the tools they name. Handlers depend only on
+// the request arguments, so a call gives the same result over every transport serving mcpServer.
+func (s *SimpleMemoryServer) RegisterTools(mcpServer *server.MCPServer) {
+	registered := map[string]bool{}
+	addTool := func(tool mcp.Tool, handler server.ToolHandlerFunc) {
+		if description, ok := s.toolDescriptions[tool.Name]; ok {
+			tool.Description = description
+		}
+		registered[tool.Name] = true
+		mcpServer.AddTool(tool, handler)
+	}
+	addTool(
+		mcp.NewTool(
+			"simple_memory_add",
+			mcp.WithDescription("Append a memory string to the simple-memory database. Either memory or template is required."),
+			mcp.WithString("memory", mcp.Description("The memory to add (string). Required unless template is given.")),
+			mcp.WithString("template", mcp.Description("Optional name of a template to render the memory content from.")),
+			mcp.WithObject("variables", mcp.Description("Variables to fill into the template (object of name to value).")),
+			mcp.WithString("remind_at", mcp.Description("Optional ISO-8601 timestamp at which the memory becomes a due reminder.")),
+			mcp.WithString("title", mcp.Description("Optional title for the memory.")),
+			mcp.WithString("tags", mcp.Description("Optional tags for the memory (comma-separated).")),
+			mcp.WithString("status", mcp.Description("Optional status for the memory (e.g., completed, issue, etc.).")),
+		),
+		s.SimpleMemoryAdd,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_get",
+			mcp.WithDescription("Get a single simple-memory by ID, as a JSON object, or just its content ready to display, e.g. a stored snippet in a fenced code block."),
+			mcp.WithNumber("id", mcp.Required(), mcp.Description("ID of the memory to get.")),
+			mcp.WithString("format", mcp.Enum("json", "plain", "markdown_code"), mcp.Description("json (default) returns the whole memory; plain returns only its content; markdown_code returns its content in a Markdown code fence.")),
+			mcp.WithString("language", mcp.Description("Language hint for the markdown_code fence, e.g. go or sql.")),
+		),
+		s.SimpleMemoryGet,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_update",
+			mcp.WithDescription("Update fields of an existing simple-memory by ID. Only provided fields are changed; the ID and created_at are kept."),
+			mcp.WithNumber("id", mcp.Required(), mcp.Description("ID of the memory to update.")),
+			mcp.WithString("memory", mcp.Description("New memory content.")),
+			mcp.WithString("title", mcp.Description("New title for the memory.")),
+			mcp.WithString("tags", mcp.Description("New tags for the memory (comma-separated).")),
+			mcp.WithString("status", mcp.Description("New status for the memory (e.g., completed, issue, etc.).")),
+			mcp.WithString("remind_at", mcp.Description("New ISO-8601 reminder timestamp; empty string clears the reminder.")),
+		),
+		s.SimpleMemoryUpdate,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_list",
+			mcp.WithDescription("List simple-memories page by page as JSON, including the total count."),
+			mcp.WithNumber("limit", mcp.Description("Maximum number of memories to return (default 50; 0 or less means no limit).")),
+			mcp.WithNumber("offset", mcp.Description("Number of memories to skip (default 0).")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses, e.g. [\"open\", \"in-progress\"]. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp (e.g. 2024-06-07T00:00:00Z).")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+			mcp.WithBoolean("explain", mcp.Description("Return the SQL, bound parameters, and query plan instead of running the query.")),
+		),
+		s.SimpleMemoryList,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_search",
+			mcp.WithDescription("Search simple-memories by title, tags, status, or content, ranked by relevance. Multi-word queries match memories containing every word."),
+			mcp.WithString("query", mcp.Required(), mcp.Description("Words (or a substring, when full-text search is unavailable) to search for in title, tags, status, or content.")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses, e.g. [\"open\", \"in-progress\"]. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp (e.g. 2024-06-07T00:00:00Z).")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+			mcp.WithBoolean("explain", mcp.Description("Return the SQL, bound parameters, and query plan instead of running the search.")),
+		),
+		s.SimpleMemorySearch,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_count",
+			mcp.WithDescription("Count simple-memories, optionally matching a query and filters, with a per-status breakdown. Cheaper than listing."),
+			mcp.WithString("query", mcp.Description("Optional words to match, as in simple_memory_search.")),
+			mcp.WithString("tags", mcp.Description("Only count memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only count memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only count memories having any one of these statuses, e.g. [\"open\", \"in-progress\"]. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only count memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only count memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only count memories created at or after this ISO-8601 timestamp.")),
+			mcp.WithString("until", mcp.Description("Only count memories created before this ISO-8601 timestamp.")),
+		),
+		s.SimpleMemoryCount,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_filter_ids",
+			mcp.WithDescription("Narrow a list of candidate simple-memory IDs, e.g. from another retrieval stage, to those also matching a query and filters. Results keep the order of ids."),
+			mcp.WithArray("ids", mcp.Required(), mcp.WithNumberItems(), mcp.Description("Candidate memory IDs.")),
+			mcp.WithString("query", mcp.Description("Optional words to match, as in simple_memory_search.")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp.")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+		),
+		s.SimpleMemoryFilterIDs,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_extremes",
+			mcp.WithDescription("List the longest and shortest simple-memories by content length in characters, to find bloated or stub entries."),
+			mcp.WithNumber("limit", mcp.Description("How many memories to list at each end (default 5).")),
+			mcp.WithString("query", mcp.Description("Optional words to match, as in simple_memory_search.")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp.")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+		),
+		s.SimpleMemoryExtremes,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_delete",
+			mcp.WithDescription("Delete all simple-memories matching the query substring in title, tags, status, or content."),
+			mcp.WithString("query", mcp.Required(), mcp.Description("Substring to match for deletion in title, tags, status, or content.")),
+			mcp.WithString("confirm_token", mcp.Description("Token returned by a previous call with the same query, when the server requires deletes to be confirmed.")),
+		),
+		s.SimpleMemoryDelete,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_delete_by_id",
+			mcp.WithDescription("Delete exactly one simple-memory by its ID. Prefer this over simple_memory_delete when the ID is known."),
+			mcp.WithNumber("id", mcp.Required(), mcp.Description("ID of the memory to delete.")),
+			mcp.WithString("confirm_token", mcp.Description("Token returned by a previous call with the same id, when the server requires deletes to be confirmed.")),
+		),
+		s.SimpleMemoryDeleteByID,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_similar",
+			mcp.WithDescription("Rank all other simple-memories by similarity (word and tag overlap; embeddings are not used) to the memory with the given ID, with scores from 0 to 1."),
+			mcp.WithNumber("id", mcp.Required(), mcp.Description("ID of the reference memory.")),
+			mcp.WithNumber("top_k", mcp.Description("Return only the k most similar memories (default: all).")),
+		),
+		s.SimpleMemorySimilar,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_near_duplicates",
+			mcp.WithDescription("Group simple-memories whose contents are near-duplicates, including reworded ones, by word-shingle Jaccard similarity. Returns clusters with every pairwise score so the threshold can be tuned before merging or deleting."),
+			mcp.WithNumber("threshold", mcp.Description("Minimum similarity, from 0 to 1, for two memories to be clustered (default 0.6). Lower it to catch looser rewordings.")),
+			mcp.WithNumber("shingle_size", mcp.Description("Words per shingle (default 2). 1 compares plain word sets, which tolerates reordering best.")),
+			mcp.WithString("query", mcp.Description("Optional words to match, as in simple_memory_search.")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp.")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+		),
+		s.SimpleMemoryNearDuplicates,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_scan_pii",
+			mcp.WithDescription("Scan simple-memories for likely personal data such as email addresses, phone numbers, and credit card numbers, e.g. for a compliance audit. Returns the IDs and PII categories found, with matched values masked."),
+			mcp.WithArray("categories", mcp.WithStringItems(), mcp.Description("Only run these detectors, e.g. [\"email\"]. Defaults to every configured category.")),
+			mcp.WithString("query", mcp.Description("Optional words to match, as in simple_memory_search.")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp.")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+		),
+		s.SimpleMemoryScanPII,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_export_db",
+			mcp.WithDescription("Copy the simple-memories matching a query and filters into a new standalone SQLite database file with the same schema, e.g. to split off one project's memories."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Path of the new database file. Must not exist yet.")),
+			mcp.WithString("query", mcp.Description("Optional words to match, as in simple_memory_search.")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp.")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+		),
+		s.SimpleMemoryExportDB,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_archive_and_clear",
+			mcp.WithDescription("Export all simple-memories to a JSON file and then delete them, to start fresh. Nothing is deleted if the export fails."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("File path to write the JSON export to. Must not already exist.")),
+			mcp.WithBoolean("confirm", mcp.Required(), mcp.Description("Must be true to confirm clearing all simple-memories.")),
+		),
+		s.SimpleMemoryArchiveAndClear,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_digest",
+			mcp.WithDescription("Consolidate the simple-memories matching a query and/or tags into a single new digest memory that references the originals. Optionally marks the originals as archived."),
+			mcp.WithString("query", mcp.Description("Words to match, as in simple_memory_search. Required unless tags is given.")),
+			mcp.WithString("tags", mcp.Description("Only digest memories having all of these tags (comma-separated, whole-tag match). Required unless query is given.")),
+			mcp.WithString("title", mcp.Description("Title of the digest memory. Defaults to \"Digest: \" followed by the query and tags.")),
+			mcp.WithBoolean("archive", mcp.Description("Set the status of the digested memories to \"archived\". Defaults to false.")),
+			mcp.WithBoolean("dry_run", mcp.Description("Return the digest that would be created without changing anything. Defaults to false.")),
+		),
+		s.SimpleMemoryDigest,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_transform",
+			mcp.WithDescription("Rewrite the content of every simple-memory matching a query and/or tags, with a Go template or a literal find/replace, in one transaction. Use dry_run to preview before/after."),
+			mcp.WithString("query", mcp.Description("Words to match, as in simple_memory_search. Required unless tags is given.")),
+			mcp.WithString("tags", mcp.Description("Only transform memories having all of these tags (comma-separated, whole-tag match). Required unless query is given.")),
+			mcp.WithString("template", mcp.Description("Go template producing the new content, with the memory as data, e.g. \"## Notes\\n{{.Content}}\". Fields: .ID, .Title, .Tags, .Status, .Content, .CreatedAt.")),
+			mcp.WithString("find", mcp.Description("Literal text to replace in the content. Used instead of template.")),
+			mcp.WithString("replace", mcp.Description("Replacement for every occurrence of find. Defaults to empty.")),
+			mcp.WithBoolean("dry_run", mcp.Description("Preview the changes without writing them. Defaults to false.")),
+			mcp.WithNumber("sample", mcp.Description("How many before/after pairs to include in the preview. Defaults to 3.")),
+		),
+		s.SimpleMemoryTransform,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_tag_matches",
+			mcp.WithDescription("Add tags to every simple-memory matching a search, in one transaction. Use dry_run to see which IDs match, then pass those ids to tag exactly them even if more memories match by then."),
+			mcp.WithString("query", mcp.Required(), mcp.Description("Words to match, as in simple_memory_search.")),
+			mcp.WithString("add_tags", mcp.Required(), mcp.Description("Tags to add to each match (comma-separated). Tags a memory already has are not repeated.")),
+			mcp.WithArray("ids", mcp.WithNumberItems(), mcp.Description("Only tag these IDs, e.g. those returned by a dry run. Memories no longer matching are skipped.")),
+			mcp.WithString("tags", mcp.Description("Only include memories having all of these tags (comma-separated, whole-tag match).")),
+			mcp.WithString("status", mcp.Description("Only include memories with exactly this status.")),
+			mcp.WithArray("statuses", mcp.WithStringItems(), mcp.Description("Only include memories having any one of these statuses. An empty list does not filter.")),
+			mcp.WithString("exclude_tags", mcp.Description("Only include memories having none of these tags (comma-separated, whole-tag match).")),
+			mcp.WithBoolean("untagged", mcp.Description("Only include memories without any tags. Cannot be combined with tags.")),
+			mcp.WithString("since", mcp.Description("Only include memories created at or after this ISO-8601 timestamp.")),
+			mcp.WithString("until", mcp.Description("Only include memories created before this ISO-8601 timestamp.")),
+			mcp.WithBoolean("dry_run", mcp.Description("Report the matching IDs and how many would change without writing. Defaults to false.")),
+		),
+		s.SimpleMemoryTagMatches,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_export",
+			mcp.WithDescription("Export every simple-memory, including IDs and created_at timestamps, as a JSON array for backup or migration with simple_memory_import."),
+		),
+		s.SimpleMemoryExport,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_import",
+			mcp.WithDescription("Import simple-memories from a simple_memory_export JSON array in a single transaction. created_at is preserved; new IDs are assigned. Any invalid entry aborts the whole import."),
+			mcp.WithString("memories", mcp.Required(), mcp.Description("The output of simple_memory_export: a JSON array of memories, passed as a string.")),
+			mcp.WithString("mode", mcp.Enum("append", "replace"), mcp.Description("append (default) adds to the existing memories; replace deletes them all first and needs at least one memory to import.")),
+		),
+		s.SimpleMemoryImport,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_import_markdown",
+			mcp.WithDescription("Import Markdown notes as simple-memories. YAML front matter supplies title, tags and status; the body is the content. Imports a single file or every .md file in a directory, all or nothing."),
+			mcp.WithString("path", mcp.Required(), mcp.Description("Markdown file, or directory of .md files, to import.")),
+			mcp.WithString("split", mcp.Enum("file", "section"), mcp.Description("Create one memory per file (default) or one per level 1 or 2 heading.")),
+		),
+		s.SimpleMemoryImportMarkdown,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_trends",
+			mcp.WithDescription("Count simple-memories created per tag or status in each day, ISO week, or month, for trend charts."),
+			mcp.WithString("group_by", mcp.Enum("tag", "status"), mcp.Description("Group counts by tag or status (default: tag).")),
+			mcp.WithString("bucket", mcp.Enum("day", "week", "month"), mcp.Description("Time bucket size (default: day).")),
+		),
+		s.SimpleMemoryTrends,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_due_reminders",
+			mcp.WithDescription("List simple-memories whose reminder time has arrived and that have not been acknowledged yet."),
+			mcp.WithString("as_of", mcp.Description("Optional ISO-8601 timestamp to check against instead of now.")),
+		),
+		s.SimpleMemoryDueReminders,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_ack_reminder",
+			mcp.WithDescription("Acknowledge a due reminder so it is no longer reported."),
+			mcp.WithNumber("id", mcp.Required(), mcp.Description("ID of the memory whose reminder to acknowledge.")),
+		),
+		s.SimpleMemoryAckReminder,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_fix_timestamps",
+			mcp.WithDescription("Report created_at values that are malformed (not in the canonical format, which breaks time sorting), unparseable, or out of order relative to ID order. With fix, malformed values are normalized."),
+			mcp.WithBoolean("fix", mcp.Description("Rewrite malformed created_at values in the canonical format. Defaults to false (report only).")),
+		),
+		s.SimpleMemoryFixTimestamps,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_find_mojibake",
+			mcp.WithDescription("Find simple-memories whose title, tags, or content contain replacement characters (U+FFFD), invalid UTF-8, or double-encoded UTF-8."),
+		),
+		s.SimpleMemoryFindMojibake,
+	)
+	addTool(
+		mcp.NewTool(
+			"simple_memory_storage_stats",
+			mcp.WithDescription("Report the database's page count, free pages left behind by deletions, and fragmentation, and whether VACUUM is worthwhile. With vacuum, run VACUUM when it is recommended."),
+			mcp.WithNumber("threshold", mcp.Description("Fraction of free pages, from 0 to 1, at which VACUUM is recommended (default 0.2).")),
+			mcp.WithBoolean("vacuum", mcp.Description("Run VACUUM if it is recommended. Defaults to false (report only). VACUUM rewrites the whole file and blocks writes while it runs.")),
+		),
+		s.SimpleMemoryStorageStats,
+	)
+
+	for name := range s.toolDescriptions {
+		if !registered[name] && !s.disableLogging {
+			s.logger.Printf("[WARN] Ignoring description override for unknown tool %q", name)
+		}
+	}
+}
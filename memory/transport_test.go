@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// transportClients starts every transport main can serve on for mcpServer, plus the in-process
+// one, and returns an initialized client for each, keyed by transport name.
+func transportClients(t *testing.T, mcpServer *server.MCPServer) map[string]*client.Client {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	clients := map[string]*client.Client{}
+
+	inProcess, err := client.NewInProcessClient(mcpServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clients["in-process"] = inProcess
+
+	// stdio: the server reads requests from one pipe and writes responses to another.
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+	go func() { _ = server.NewStdioServer(mcpServer).Listen(ctx, reqR, respW) }()
+	t.Cleanup(func() { reqW.Close(); respW.Close() })
+	clients["stdio"] = client.NewClient(transport.NewIO(respR, reqW, io.NopCloser(strings.NewReader(""))))
+
+	httpSrv := httptest.NewServer(server.NewStreamableHTTPServer(mcpServer))
+	t.Cleanup(httpSrv.Close)
+	if clients["http"], err = client.NewStreamableHttpClient(httpSrv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	var sse *server.SSEServer
+	sseSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { sse.ServeHTTP(w, r) }))
+	t.Cleanup(sseSrv.Close)
+	sse = server.NewSSEServer(mcpServer, server.WithBaseURL(sseSrv.URL))
+	if clients["sse"], err = client.NewSSEMCPClient(sseSrv.URL + "/sse"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, c := range clients {
+		t.Cleanup(func() { c.Close() })
+		if err := c.Start(ctx); err != nil {
+			t.Fatalf("%s: start: %v", name, err)
+		}
+		initCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		_, err := c.Initialize(initCtx, mcp.InitializeRequest{Params: mcp.InitializeParams{ProtocolVersion: mcp.LATEST_PROTOCOL_VERSION}})
+		cancel()
+		if err != nil {
+			t.Fatalf("%s: initialize: %v", name, err)
+		}
+	}
+	return clients
+}
+
+// TestTransportConsistency checks the transport-agnostic contract: a tool call returns the same
+// text and error flag whether its handler is called directly or the call arrives over the
+// in-process, stdio, streamable HTTP, or SSE transport.
+func TestTransportConsistency(t *testing.T) {
+	s := newTestServer(t, DefaultConfig())
+	mustAdd(t, s,
+		map[string]any{"memory": "User prefers the chi router", "title": "Router", "tags": "go, http", "status": "open"},
+		map[string]any{"memory": "Use pgx for Postgres", "tags": "go, db"},
+		map[string]any{"memory": "Ünïcödé ☕ notes", "status": "done"},
+	)
+	mcpServer := server.NewMCPServer("test", "1.0.0", server.WithToolCapabilities(true))
+	s.RegisterTools(mcpServer)
+	clients := transportClients(t, mcpServer)
+
+	calls := []struct {
+		handler server.ToolHandlerFunc
+		tool    string
+		args    map[string]any
+	}{
+		{s.SimpleMemoryGet, "simple_memory_get", map[string]any{"id": 1}},
+		{s.SimpleMemoryGet, "simple_memory_get", map[string]any{"id": 99}},
+		{s.SimpleMemoryList, "simple_memory_list", map[string]any{"tags": "go", "limit": 1}},
+		{s.SimpleMemorySearch, "simple_memory_search", map[string]any{"query": "chi"}},
+		{s.SimpleMemorySearch, "simple_memory_search", map[string]any{"query": "☕"}},
+		{s.SimpleMemorySearch, "simple_memory_search", map[string]any{"query": "absent"}},
+		{s.SimpleMemorySearch, "simple_memory_search", map[string]any{"query": " "}},
+		{s.SimpleMemoryCount, "simple_memory_count", map[string]any{"statuses": []any{"open", "done"}}},
+		{s.SimpleMemoryFilterIDs, "simple_memory_filter_ids", map[string]any{"ids": []any{3, 1}, "untagged": true}},
+		{s.SimpleMemorySimilar, "simple_memory_similar", map[string]any{"id": 1}},
+		{s.SimpleMemoryExtremes, "simple_memory_extremes", map[string]any{"limit": 1}},
+		{s.SimpleMemoryExport, "simple_memory_export", nil},
+	}
+	for _, call := range calls {
+		wantText, wantErr := callTool(t, call.handler, call.args)
+		for name, c := range clients {
+			req := mcp.CallToolRequest{}
+			req.Params.Name = call.tool
+			req.Params.Arguments = call.args
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			res, err := c.CallTool(ctx, req)
+			cancel()
+			if err != nil {
+				t.Fatalf("%s %s %v: %v", name, call.tool, call.args, err)
+			}
+			var text []string
+			for _, content := range res.Content {
+				if tc, ok := content.(mcp.TextContent); ok {
+					text = append(text, tc.Text)
+				}
+			}
+			if got := strings.Join(text, ""); got != wantText || res.IsError != wantErr {
+				t.Errorf("%s %s %v: got %q (error=%v), want %q (error=%v)", name, call.tool, call.args, got, res.IsError, wantText, wantErr)
+			}
+		}
+	}
+}